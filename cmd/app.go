@@ -3,61 +3,120 @@ package cmd
 import (
 	"bufio"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	"path"
 	"strings"
 
 	"github.com/fatih/color"
 	"github.com/pkg/errors"
 )
 
+// remoteDir holds the plugin's state on the server. It lives outside of
+// ~/buffaloproject so a git pull can never clobber it.
+const remoteDir = "/root/.buffalo-ocean"
+
+// remoteEnvFile is the env file handed to every buffaloweb container.
+const remoteEnvFile = remoteDir + "/env.list"
+
+// requestUserInput asks msg and returns the answer. Under --non-interactive
+// stdin is never read and the answer is empty, callers check up front that
+// nothing they need is missing.
 func requestUserInput(msg string) string {
-	reader := bufio.NewReader(os.Stdin)
 	color.Yellow("\n%s", msg)
+	switch {
+	case dryRun:
+		fmt.Println("    (asked when run for real)")
+		return ""
+	case nonInteractive:
+		fmt.Println("    (skipped, --non-interactive)")
+		return ""
+	}
+	reader := bufio.NewReader(os.Stdin)
 	key, _ := reader.ReadString('\n')
 	return strings.TrimSpace(key)
 }
 
 func remoteCmd(cmd string) error {
-	c := exec.Command("docker-machine", "ssh", serverName, cmd)
-	c.Stdout = os.Stdout
-	c.Stderr = os.Stderr
-	c.Stdin = os.Stdin
-
-	if err := c.Run(); err != nil {
+	if err := executor.Exec(cmd, os.Stdin, os.Stdout, os.Stderr); err != nil {
 		return errors.WithStack(err)
 	}
 	return nil
 }
 
 func copyFileToMachine(file, dir string) error {
-	d := fmt.Sprintf("%s:%s", serverName, dir)
-	c := exec.Command("docker-machine", "scp", file, d)
-	c.Stdout = os.Stdout
-	c.Stderr = os.Stderr
-	c.Stdin = os.Stdin
+	if err := executor.Copy(file, dir); err != nil {
+		return errors.WithStack(err)
+	}
 
-	if err := c.Run(); err != nil {
+	return nil
+}
+
+func copyFileFromMachine(file, dst string) error {
+	if err := executor.Download(file, dst); err != nil {
 		return errors.WithStack(err)
 	}
 
 	return nil
 }
 
-func copyFileToRemoteProject(file string) error {
-	p := fmt.Sprintf("%s:~/buffaloproject/", serverName)
-	c := exec.Command("docker-machine", "scp", file, p)
-	c.Stdout = os.Stdout
-	c.Stderr = os.Stderr
-	c.Stdin = os.Stdin
+// writeRemoteFile replaces dst on the server with content, readable by root
+// only.
+func writeRemoteFile(content, dst string) error {
+	f, err := ioutil.TempFile("", "buffalo-ocean")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer os.Remove(f.Name())
 
-	if err := c.Run(); err != nil {
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return errors.WithStack(err)
+	}
+	if err := f.Close(); err != nil {
 		return errors.WithStack(err)
 	}
 
+	if err := remoteCmd(fmt.Sprintf("mkdir -p %s", path.Dir(dst))); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := copyFileToMachine(f.Name(), dst); err != nil {
+		return errors.WithStack(err)
+	}
+	return remoteCmd(fmt.Sprintf("chmod 600 %s", dst))
+}
+
+// ensureRemoteEnvFile makes sure the env file exists on the server, moving
+// one left in the project checkout by earlier versions of the plugin.
+func ensureRemoteEnvFile() error {
+	legacy := "/root/buffaloproject/env.list"
+	cmd := fmt.Sprintf("bash -c \"mkdir -p %s && if [ ! -f %s ] && [ -f %s ]; then mv %s %s; fi && touch %s && chmod 600 %s\"", remoteDir, remoteEnvFile, legacy, legacy, remoteEnvFile, remoteEnvFile, remoteEnvFile)
+
+	if err := remoteCmd(cmd); err != nil {
+		return errors.WithStack(err)
+	}
 	return nil
 }
 
+// remoteTest reports whether the shell condition cond holds on the server.
+func remoteTest(cond string) bool {
+	out, _ := remoteOutput(fmt.Sprintf("bash -c \"if %s; then echo yes; fi\"", cond))
+	return strings.TrimSpace(out) == "yes"
+}
+
+// skipStep reports that a setup step is skipped because its result is
+// already present on the server.
+func skipStep(what string) {
+	color.Yellow("\n==> SKIPPING: %s, already present", what)
+}
+
+// shellQuote wraps s in single quotes so the remote shell passes it on
+// untouched.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'"'"'`, -1) + "'"
+}
+
 func validateGit() error {
 	c := exec.Command("git", "status")
 	b, err := c.CombinedOutput()
@@ -70,8 +129,8 @@ func validateGit() error {
 }
 
 func displayServerInfo() error {
-	ip, _ := exec.Command("docker-machine", "ip", serverName).Output()
-	fmt.Printf("\nssh root@%s -i ~/.docker/machine/machines/%s/id_rsa", strings.TrimSpace(string(ip)), serverName)
+	ip, _ := executor.IP()
+	fmt.Printf("\n%s", executor.Login())
 	fmt.Printf("\nopen http://%s\n", ip)
 
 	return nil
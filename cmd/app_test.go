@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestRequestUserInputNonInteractive(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := w.WriteString("yes\n"); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	prevStdin, prevNonInteractive := os.Stdin, nonInteractive
+	defer func() { os.Stdin, nonInteractive = prevStdin, prevNonInteractive }()
+	os.Stdin, nonInteractive = r, true
+
+	if got := requestUserInput("Continue?"); got != "" {
+		t.Errorf("got %q, want an empty answer", got)
+	}
+	if rest, _ := ioutil.ReadAll(r); string(rest) != "yes\n" {
+		t.Errorf("stdin was read, %q left", rest)
+	}
+}
@@ -0,0 +1,320 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// remoteBackupDir holds the database backups taken on the server.
+const remoteBackupDir = remoteDir + "/backups"
+
+// remoteBackupScript is run by cron for the nightly backups.
+const remoteBackupScript = remoteDir + "/backup.sh"
+
+const backupCronFile = "/etc/cron.d/buffalo-ocean-backup"
+
+// Backups are prefixed with the reason they were taken, retention only ever
+// applies to the automatic ones.
+const (
+	manualBackup    = "manual"
+	nightlyBackup   = "nightly"
+	preDeployBackup = "pre-deploy"
+)
+
+var dbBackupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Dump the database on the server and download the dump",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return dbProject.backupDatabase()
+	},
+}
+
+var dbRestoreCmd = &cobra.Command{
+	Use:   "restore FILE",
+	Short: "Replace the database with a dump, either a local file or a backup on the server",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return dbProject.restoreDatabase(args[0])
+	},
+}
+
+var dbBackupsCmd = &cobra.Command{
+	Use:   "backups",
+	Short: "Manage the database backups kept on the server",
+}
+
+var dbBackupsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the database backups kept on the server",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dbProject.connect()
+		return listBackups()
+	},
+}
+
+var dbBackupsScheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Install a nightly backup cron job on the server",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return dbProject.scheduleBackups()
+	},
+}
+
+var dbBackupsUnscheduleCmd = &cobra.Command{
+	Use:   "unschedule",
+	Short: "Remove the nightly backup cron job from the server",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dbProject.connect()
+		color.Blue("\n==> Removing the nightly backup")
+		return remoteCmd(fmt.Sprintf("rm -f %s %s", backupCronFile, remoteBackupScript))
+	},
+}
+
+var backupOutputDir string
+var backupRemoteOnly bool
+var restoreConfirmed bool
+var backupKeep int
+var backupAt string
+
+func init() {
+	dbBackupCmd.Flags().StringVarP(&backupOutputDir, "output-dir", "o", ".", "Local directory the backup is downloaded to")
+	dbBackupCmd.Flags().BoolVar(&backupRemoteOnly, "remote-only", false, "Keep the backup on the server without downloading it")
+	dbRestoreCmd.Flags().BoolVarP(&restoreConfirmed, "yes", "y", false, "Restore without asking for confirmation")
+	dbBackupsScheduleCmd.Flags().IntVar(&backupKeep, "keep", 7, "Number of nightly backups to keep on the server")
+	dbBackupsScheduleCmd.Flags().StringVar(&backupAt, "at", "03:00", "Time of day (HH:MM, server time) the backup runs at")
+
+	dbBackupsCmd.AddCommand(dbBackupsListCmd, dbBackupsScheduleCmd, dbBackupsUnscheduleCmd)
+	dbCmd.AddCommand(dbBackupCmd, dbRestoreCmd, dbBackupsCmd)
+}
+
+// backupEngine returns the engine of p, failing for the ones that can not
+// be dumped.
+func (p Project) backupEngine() (dbEngine, error) {
+	e, err := p.dbEngine()
+	if err != nil {
+		return e, errors.WithStack(err)
+	}
+	if e.Dump == nil || e.Restore == nil {
+		return e, errors.Errorf("backups are not supported for the %s database", e.Name)
+	}
+	return e, nil
+}
+
+// backupExt is the file extension of the dumps of e.
+func backupExt(e dbEngine) string {
+	if e.Name == "sqlite" {
+		return ".sqlite.gz"
+	}
+	return ".sql.gz"
+}
+
+// createBackup dumps the database into the backup directory on the server
+// and returns the path of the dump.
+func createBackup(p Project, kind string) (string, error) {
+	e, err := p.backupEngine()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	c, err := ensureDBCredentials(p, false)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	f := path.Join(remoteBackupDir, fmt.Sprintf("%s-%s%s", kind, now().UTC().Format("20060102150405"), backupExt(e)))
+	color.Blue("\n==> BACKING UP: %s to %s", c.Name, f)
+	cmd := fmt.Sprintf("bash -c \"set -o pipefail && mkdir -p %s && chmod 700 %s && %s | gzip > %s || { rm -f %s; exit 1; }\"", remoteBackupDir, remoteBackupDir, e.Dump(c), f, f)
+	if err := remoteCmd(cmd); err != nil {
+		return "", errors.Wrap(err, "could not back up the database")
+	}
+	return f, nil
+}
+
+// pruneBackups removes all but the newest keep backups of the given kind.
+func pruneBackups(kind string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	cmd := fmt.Sprintf("bash -c \"ls -1t %s/%s-* 2>/dev/null | tail -n +%d | xargs -r rm -f --\"", remoteBackupDir, kind, keep+1)
+	return remoteCmd(cmd)
+}
+
+func (p Project) backupDatabase() error {
+	p.connect()
+
+	f, err := createBackup(p, manualBackup)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if backupRemoteOnly {
+		return nil
+	}
+
+	if err := os.MkdirAll(backupOutputDir, 0700); err != nil {
+		return errors.WithStack(err)
+	}
+	local := filepath.Join(backupOutputDir, fmt.Sprintf("%s-%s", serverName, strings.TrimPrefix(path.Base(f), manualBackup+"-")))
+	color.Blue("\n==> DOWNLOADING: %s", local)
+	if err := copyFileFromMachine(f, local); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (p Project) restoreDatabase(file string) error {
+	p.connect()
+
+	e, err := p.backupEngine()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	c, err := ensureDBCredentials(p, false)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if !restoreConfirmed {
+		if nonInteractive {
+			return errors.New("restoring replaces the data of the database, pass --yes to confirm")
+		}
+		a := requestUserInput(fmt.Sprintf("This replaces the data of the %s database on %s with %s. Continue? [y/N]", c.Name, serverName, file))
+		if !strings.HasPrefix(strings.ToLower(a), "y") {
+			return errors.New("restore aborted")
+		}
+	}
+
+	remote := path.Join(remoteBackupDir, path.Base(file))
+	if _, err := os.Stat(file); err == nil {
+		remote = path.Join(remoteBackupDir, "upload-"+filepath.Base(file))
+		color.Blue("\n==> UPLOADING: %s", file)
+		if err := remoteCmd(fmt.Sprintf("bash -c \"mkdir -p %s && chmod 700 %s\"", remoteBackupDir, remoteBackupDir)); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := copyFileToMachine(file, remote); err != nil {
+			return errors.WithStack(err)
+		}
+		defer remoteCmd(fmt.Sprintf("rm -f %s", remote))
+	} else if _, err := remoteOutput(fmt.Sprintf("test -f %s", remote)); err != nil {
+		return errors.Errorf("%s is neither a local file nor a backup on the server, see \"db backups list\"", file)
+	}
+
+	read := "cat"
+	if strings.HasSuffix(remote, ".gz") {
+		read = "gunzip -c"
+	}
+	color.Blue("\n==> RESTORING: %s into %s", path.Base(remote), c.Name)
+	if err := remoteCmd(fmt.Sprintf("bash -c \"set -o pipefail && %s %s | %s\"", read, remote, e.Restore(c))); err != nil {
+		return errors.Wrap(err, "could not restore the database")
+	}
+	return restartWebContainer(p)
+}
+
+// backupFile is a dump kept in the backup directory on the server.
+type backupFile struct {
+	Name    string
+	Size    int64
+	Created string
+}
+
+func listBackups() error {
+	out, err := remoteOutput(fmt.Sprintf("bash -c \"find %s -maxdepth 1 -type f -printf '%%f\\t%%s\\t%%TY-%%Tm-%%Td %%TH:%%TM\\n' 2>/dev/null || true\"", remoteBackupDir))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var bs []backupFile
+	for _, l := range strings.Split(strings.TrimSpace(out), "\n") {
+		f := strings.Split(l, "\t")
+		if len(f) != 3 {
+			continue
+		}
+		size, _ := strconv.ParseInt(f[1], 10, 64)
+		bs = append(bs, backupFile{Name: f[0], Size: size, Created: f[2]})
+	}
+	if len(bs) == 0 {
+		color.Yellow("No backups have been taken yet.")
+		return nil
+	}
+	sort.Slice(bs, func(i, j int) bool { return bs[i].Created > bs[j].Created })
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BACKUP\tSIZE\tCREATED")
+	for _, b := range bs {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", b.Name, humanSize(b.Size), b.Created)
+	}
+	return errors.WithStack(w.Flush())
+}
+
+func humanSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// backupScript is the script cron runs for the nightly backups. The
+// password is read from the stored credentials when the backup runs so a
+// rotated password is picked up.
+func backupScript(e dbEngine, c dbCredentials, keep int) string {
+	var b strings.Builder
+	b.WriteString("#!/bin/bash\nset -eo pipefail\n")
+	if e.PasswordVar != "" {
+		fmt.Fprintf(&b, "DB_PASSWORD=$(sed -n 's/^%s=//p' %s)\n", e.PasswordVar, remoteDBEnvFile)
+		c.Password = "\"$DB_PASSWORD\""
+	}
+	fmt.Fprintf(&b, "mkdir -p %s && chmod 700 %s\n", remoteBackupDir, remoteBackupDir)
+	fmt.Fprintf(&b, "f=%s/%s-$(date -u +%%Y%%m%%d%%H%%M%%S)%s\n", remoteBackupDir, nightlyBackup, backupExt(e))
+	fmt.Fprintf(&b, "%s | gzip > \"$f\" || { rm -f \"$f\"; exit 1; }\n", e.Dump(c))
+	fmt.Fprintf(&b, "ls -1t %s/%s-* | tail -n +%d | xargs -r rm -f --\n", remoteBackupDir, nightlyBackup, keep+1)
+	return b.String()
+}
+
+func (p Project) scheduleBackups() error {
+	p.connect()
+
+	t, err := time.Parse("15:04", backupAt)
+	if err != nil {
+		return errors.Errorf("--at must be a time of day like 03:00, got %q", backupAt)
+	}
+	if backupKeep < 1 {
+		return errors.New("--keep must be at least 1")
+	}
+	e, err := p.backupEngine()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	c, err := ensureDBCredentials(p, false)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	color.Blue("\n==> Scheduling a nightly backup at %s, keeping %d", backupAt, backupKeep)
+	if err := writeRemoteFile(backupScript(e, c, backupKeep), remoteBackupScript); err != nil {
+		return errors.WithStack(err)
+	}
+	cron := fmt.Sprintf("%d %d * * * root bash %s >> /var/log/buffalo-ocean-backup.log 2>&1\n", t.Minute(), t.Hour(), remoteBackupScript)
+	if err := writeRemoteFile(cron, backupCronFile); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// envPrefix is prepended to the upper cased flag name to form the
+// environment variable that can provide the flag's value.
+const envPrefix = "BUFFALO_OCEAN_"
+
+// envAliases are well known environment variables that are also accepted
+// for a flag.
+var envAliases = map[string]string{
+	"key": "DIGITALOCEAN_ACCESS_TOKEN",
+}
+
+// configFile is the project local file holding the settings used by setup
+// so they do not have to be repeated on every command. Its keys are named
+// after the flags they provide defaults for.
+var configFile string
+
+func init() {
+	oceanCmd.PersistentFlags().StringVar(&configFile, "config", ".buffalo-ocean.yml", "Project config file providing defaults for flags")
+	oceanCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return applyConfig(cmd)
+	}
+}
+
+// readConfig loads the raw values from the config file. A missing file is
+// not an error.
+func readConfig() (map[string]interface{}, error) {
+	c := map[string]interface{}{}
+
+	b, err := ioutil.ReadFile(configFile)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err := yaml.Unmarshal(b, &c); err != nil {
+		return nil, errors.Wrapf(err, "could not parse %s", configFile)
+	}
+	return c, nil
+}
+
+// applyConfig fills in every flag of cmd that was not given on the command
+// line. Environment variables take precedence over the config file.
+func applyConfig(cmd *cobra.Command) error {
+	c, err := readConfig()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	for k, v := range c {
+		f := cmd.Flags().Lookup(k)
+		if f == nil || f.Changed || v == nil {
+			continue
+		}
+		vals := []interface{}{v}
+		if l, ok := v.([]interface{}); ok {
+			vals = l
+		}
+		for _, v := range vals {
+			if err := f.Value.Set(fmt.Sprint(v)); err != nil {
+				return errors.Wrapf(err, "invalid value for %s in %s", k, configFile)
+			}
+		}
+	}
+
+	var ferr error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Changed || ferr != nil {
+			return
+		}
+		for _, e := range flagEnvVars(f.Name) {
+			if v, ok := os.LookupEnv(e); ok {
+				if err := f.Value.Set(v); err != nil {
+					ferr = errors.Wrapf(err, "invalid value for %s", e)
+				}
+				return
+			}
+		}
+	})
+	return ferr
+}
+
+// flagEnvVars lists the environment variables that can provide a flag.
+func flagEnvVars(name string) []string {
+	e := []string{envPrefix + strings.ToUpper(strings.Replace(name, "-", "_", -1))}
+	if a, ok := envAliases[name]; ok {
+		e = append(e, a)
+	}
+	return e
+}
+
+// writeConfig saves the settings of p to the config file.
+func writeConfig(p Project) error {
+	if dryRun {
+		color.Blue("\n==> Would save project settings to %s", configFile)
+		return nil
+	}
+	b, err := yaml.Marshal(p)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := ioutil.WriteFile(configFile, b, 0644); err != nil {
+		return errors.WithStack(err)
+	}
+
+	color.Blue("\n==> Saved project settings to %s", configFile)
+	return nil
+}
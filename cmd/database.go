@@ -0,0 +1,371 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+const dbContainer = "buffalodb"
+
+// remoteDBEnvFile holds the database credentials on the server. It is handed
+// to the database container as well as to every web container, ahead of
+// the user's env file so DATABASE_URL can still be overridden there.
+const remoteDBEnvFile = remoteDir + "/db.env"
+
+const passwordChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// dbNameVar stores the database name in db.env for engines whose image
+// does not read it from an env var of its own.
+const dbNameVar = "BUFFALO_OCEAN_DATABASE"
+
+// defaultDatabase is the engine used when none is configured.
+const defaultDatabase = "postgres"
+
+// dbCredentials are the login and database name of the project database.
+// URL is only set when it can not be derived from the rest, eg. for an
+// external database.
+type dbCredentials struct {
+	User     string
+	Password string
+	Name     string
+	URL      string
+	Options  map[string]string
+}
+
+// dbEngine describes how a database is run on the server and how the app
+// connects to it. Engines without an Image do not get a database container.
+type dbEngine struct {
+	Name string
+	// Image is the pinned image the database container is started from.
+	Image string
+	// Args are appended to the docker run command after the image.
+	Args string
+	// DataDir is where the container keeps its data, /root/db_volume is
+	// mounted there.
+	DataDir string
+	// WebVolume is mounted into the web containers, when set.
+	WebVolume string
+	// UserVar, PasswordVar and NameVar are the env vars the image reads
+	// the credentials from.
+	UserVar     string
+	PasswordVar string
+	NameVar     string
+	// Env is added to the env file of the database container.
+	Env envList
+	// Credentials generates the credentials for a new database.
+	Credentials func(p Project) (dbCredentials, error)
+	// URL builds DATABASE_URL, an empty string means it is not set.
+	URL func(c dbCredentials) string
+	// Init is run once the container has been started, when set.
+	Init func(c dbCredentials) string
+	// Rotate changes the password of the user, when supported.
+	Rotate func(c dbCredentials, password string) string
+	// Dump writes a dump of the database to stdout, when supported.
+	Dump func(c dbCredentials) string
+	// Restore loads a dump read from stdin, when supported.
+	Restore func(c dbCredentials) string
+}
+
+var dbEngines = map[string]dbEngine{
+	"postgres": {
+		Name:        "postgres",
+		Image:       "postgres:11.1",
+		DataDir:     "/var/lib/postgresql/data",
+		UserVar:     "POSTGRES_USER",
+		PasswordVar: "POSTGRES_PASSWORD",
+		NameVar:     "POSTGRES_DB",
+		Credentials: generatedCredentials,
+		URL: func(c dbCredentials) string {
+			return fmt.Sprintf("postgres://%s:%s@%s:5432/%s?sslmode=disable", c.User, c.Password, dbContainer, c.Name)
+		},
+		Rotate: func(c dbCredentials, pw string) string {
+			sql := fmt.Sprintf("ALTER USER \"%s\" WITH PASSWORD '%s'", c.User, pw)
+			return fmt.Sprintf("docker container exec %s psql -U %s -d %s -c %s", dbContainer, c.User, c.Name, shellQuote(sql))
+		},
+		Dump: func(c dbCredentials) string {
+			return fmt.Sprintf("docker container exec %s pg_dump -U %s -d %s --clean --if-exists --no-owner", dbContainer, c.User, c.Name)
+		},
+		Restore: func(c dbCredentials) string {
+			return fmt.Sprintf("docker container exec -i %s psql -q -v ON_ERROR_STOP=1 -U %s -d %s", dbContainer, c.User, c.Name)
+		},
+	},
+	"mysql": {
+		Name:        "mysql",
+		Image:       "mysql:5.7.24",
+		DataDir:     "/var/lib/mysql",
+		UserVar:     "MYSQL_USER",
+		PasswordVar: "MYSQL_PASSWORD",
+		NameVar:     "MYSQL_DATABASE",
+		Env:         envList{{Key: "MYSQL_RANDOM_ROOT_PASSWORD", Value: "yes"}},
+		Credentials: generatedCredentials,
+		URL: func(c dbCredentials) string {
+			return fmt.Sprintf("mysql://%s:%s@(%s:3306)/%s?parseTime=true&multiStatements=true&readTimeout=1s", c.User, c.Password, dbContainer, c.Name)
+		},
+		Rotate: func(c dbCredentials, pw string) string {
+			sql := fmt.Sprintf("SET PASSWORD = '%s'", pw)
+			return fmt.Sprintf("docker container exec -e MYSQL_PWD=%s %s mysql -u %s -e %s", c.Password, dbContainer, c.User, shellQuote(sql))
+		},
+		Dump: func(c dbCredentials) string {
+			return fmt.Sprintf("docker container exec -e MYSQL_PWD=%s %s mysqldump --single-transaction -u %s %s", c.Password, dbContainer, c.User, c.Name)
+		},
+		Restore: func(c dbCredentials) string {
+			return fmt.Sprintf("docker container exec -i -e MYSQL_PWD=%s %s mysql -u %s %s", c.Password, dbContainer, c.User, c.Name)
+		},
+	},
+	"cockroach": {
+		Name:    "cockroach",
+		Image:   "cockroachdb/cockroach:v2.1.1",
+		Args:    "start --insecure",
+		DataDir: "/cockroach/cockroach-data",
+		Credentials: func(p Project) (dbCredentials, error) {
+			return dbCredentials{User: "root", Name: p.databaseName()}, nil
+		},
+		URL: func(c dbCredentials) string {
+			return fmt.Sprintf("cockroach://%s@%s:26257/%s?sslmode=disable", c.User, dbContainer, c.Name)
+		},
+		Init: func(c dbCredentials) string {
+			return fmt.Sprintf("bash -c \"sleep 5 && docker container exec %s ./cockroach sql --insecure -e 'CREATE DATABASE IF NOT EXISTS %s'\"", dbContainer, c.Name)
+		},
+		Dump: func(c dbCredentials) string {
+			return fmt.Sprintf("docker container exec %s ./cockroach dump %s --insecure", dbContainer, c.Name)
+		},
+		Restore: func(c dbCredentials) string {
+			return fmt.Sprintf("docker container exec -i %s ./cockroach sql --insecure -d %s", dbContainer, c.Name)
+		},
+	},
+	"sqlite": {
+		Name:      "sqlite",
+		WebVolume: "/root/db_volume:/data",
+		Credentials: func(p Project) (dbCredentials, error) {
+			return dbCredentials{Name: p.databaseName()}, nil
+		},
+		URL: func(c dbCredentials) string {
+			return fmt.Sprintf("sqlite3:///data/%s.sqlite", c.Name)
+		},
+		Dump: func(c dbCredentials) string {
+			return fmt.Sprintf("cat /root/db_volume/%s.sqlite", c.Name)
+		},
+		Restore: func(c dbCredentials) string {
+			return fmt.Sprintf("cat > /root/db_volume/%s.sqlite", c.Name)
+		},
+	},
+	"external": {
+		Name: "external",
+		Credentials: func(p Project) (dbCredentials, error) {
+			if p.DatabaseURL == "" {
+				return dbCredentials{}, errors.New("--database-url is required for an external database")
+			}
+			return dbCredentials{URL: p.DatabaseURL}, nil
+		},
+		URL: func(c dbCredentials) string {
+			return c.URL
+		},
+	},
+	"none": {
+		Name: "none",
+		Credentials: func(p Project) (dbCredentials, error) {
+			return dbCredentials{}, nil
+		},
+		URL: func(c dbCredentials) string {
+			return ""
+		},
+	},
+}
+
+// addDatabaseFlag adds the flag choosing the database engine, for commands
+// that need to know how the app reaches its database.
+func addDatabaseFlag(fs *pflag.FlagSet, p *Project) {
+	fs.StringVar(&p.Database, "database", "", fmt.Sprintf("The database engine used by the application (%s). Defaults to the dialect in database.yml or %s", dbEngineNames(), defaultDatabase))
+	fs.StringVar(&p.DatabaseName, "database-name", "", "The name of the database. Defaults to the one in database.yml or buffalo_<environment>")
+}
+
+// dbEngineNames lists the supported engines for help texts and errors.
+func dbEngineNames() string {
+	var n []string
+	for k := range dbEngines {
+		n = append(n, k)
+	}
+	sort.Strings(n)
+	return strings.Join(n, ", ")
+}
+
+// dbEngine returns the database engine configured for p.
+func (p Project) dbEngine() (dbEngine, error) {
+	n := p.Database
+	if n == "" {
+		n = defaultDatabase
+	}
+	e, ok := dbEngines[n]
+	if !ok {
+		return e, errors.Errorf("%q is not a supported database, use one of: %s", n, dbEngineNames())
+	}
+	return e, nil
+}
+
+// databaseName is the name of the database created for p.
+func (p Project) databaseName() string {
+	if p.DatabaseName != "" {
+		return p.DatabaseName
+	}
+	return fmt.Sprintf("buffalo_%s", p.Environment)
+}
+
+func generatedCredentials(p Project) (dbCredentials, error) {
+	pw, err := randomString(32)
+	if err != nil {
+		return dbCredentials{}, errors.WithStack(err)
+	}
+	return dbCredentials{User: "buffalo", Password: pw, Name: p.databaseName()}, nil
+}
+
+// legacyDBCredentials are the credentials earlier versions of the plugin
+// hardcoded into every server.
+func legacyDBCredentials(p Project) dbCredentials {
+	return dbCredentials{User: "admin", Password: "password", Name: p.databaseName()}
+}
+
+func (e dbEngine) envList(c dbCredentials) envList {
+	var l envList
+	nameVar := e.NameVar
+	if nameVar == "" && c.Name != "" {
+		nameVar = dbNameVar
+	}
+	for _, v := range []envVar{{e.UserVar, c.User}, {e.PasswordVar, c.Password}, {nameVar, c.Name}} {
+		if v.Key != "" {
+			l = l.set(v.Key, v.Value)
+		}
+	}
+	for _, v := range e.Env {
+		l = l.set(v.Key, v.Value)
+	}
+	if u := withURLOptions(e.URL(c), c.Options); u != "" {
+		l = l.set("DATABASE_URL", u)
+	}
+	return l
+}
+
+// readDBCredentials loads the credentials stored on the server. The bool is
+// false when none have been stored yet.
+func readDBCredentials(e dbEngine) (dbCredentials, bool, error) {
+	out, err := remoteOutput(fmt.Sprintf("bash -c \"cat %s 2>/dev/null || echo '#missing'\"", remoteDBEnvFile))
+	if err != nil {
+		return dbCredentials{}, false, errors.WithStack(err)
+	}
+	if strings.HasPrefix(out, "#missing") {
+		return dbCredentials{}, false, nil
+	}
+	l, err := parseEnvFile(out)
+	if err != nil {
+		return dbCredentials{}, false, errors.WithStack(err)
+	}
+
+	var c dbCredentials
+	c.User, _ = l.get(e.UserVar)
+	c.Password, _ = l.get(e.PasswordVar)
+	if e.NameVar != "" {
+		c.Name, _ = l.get(e.NameVar)
+	} else {
+		c.Name, _ = l.get(dbNameVar)
+	}
+	c.URL, _ = l.get("DATABASE_URL")
+	c.Options = urlOptions(c.URL)
+	return c, true, nil
+}
+
+func writeDBCredentials(e dbEngine, c dbCredentials) error {
+	return writeRemoteFile(e.envList(c).envFile(), remoteDBEnvFile)
+}
+
+// ensureDBCredentials makes sure credentials are stored on the server.
+// New servers get freshly generated ones, servers set up before they were
+// stored keep using the legacy ones their database was created with.
+func ensureDBCredentials(p Project, generate bool) (dbCredentials, error) {
+	e, err := p.dbEngine()
+	if err != nil {
+		return dbCredentials{}, errors.WithStack(err)
+	}
+
+	c, ok, err := readDBCredentials(e)
+	if err != nil {
+		return c, errors.WithStack(err)
+	}
+	if ok {
+		// Servers set up before the name was stored for every engine.
+		if c.Name == "" {
+			c.Name = p.databaseName()
+		}
+		return c, nil
+	}
+
+	switch {
+	case generate:
+		color.Blue("\n==> Generating database credentials")
+		if c, err = e.Credentials(p); err != nil {
+			return c, errors.WithStack(err)
+		}
+	case e.Name == "postgres":
+		color.Yellow("\nNo stored database credentials were found, storing the legacy ones. Run \"db rotate-password\" to replace them.")
+		c = legacyDBCredentials(p)
+	default:
+		if c, err = e.Credentials(p); err != nil {
+			return c, errors.WithStack(err)
+		}
+	}
+	c.Options = p.DatabaseOptions
+	return c, writeDBCredentials(e, c)
+}
+
+// dbContainerCmd builds the docker command that starts the database
+// container for p. It is empty when the engine runs no container.
+func dbContainerCmd(p Project) string {
+	e, err := p.dbEngine()
+	if err != nil || e.Image == "" {
+		return ""
+	}
+
+	cmd := fmt.Sprintf("docker container run -it --name %s -v /root/db_volume:%s --network=buffalonet --env-file %s -d %s", dbContainer, e.DataDir, remoteDBEnvFile, e.Image)
+	if e.Args != "" {
+		cmd = fmt.Sprintf("%s %s", cmd, e.Args)
+	}
+	return cmd
+}
+
+// startDBContainer creates the database container for p and runs the
+// engine's init step, doing nothing for engines without a container.
+func startDBContainer(p Project, c dbCredentials) error {
+	cmd := dbContainerCmd(p)
+	if cmd == "" {
+		return nil
+	}
+	if err := remoteCmd(cmd); err != nil {
+		return errors.WithStack(err)
+	}
+
+	e, _ := p.dbEngine()
+	if e.Init != nil {
+		if err := remoteCmd(e.Init(c)); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	max := big.NewInt(int64(len(passwordChars)))
+	for i := range b {
+		c, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		b[i] = passwordChars[c.Int64()]
+	}
+	return string(b), nil
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDumpAndRestoreCommands(t *testing.T) {
+	tests := []struct {
+		engine  string
+		stored  string
+		dump    string
+		restore string
+	}{
+		{
+			engine:  "postgres",
+			stored:  "POSTGRES_USER=buffalo\nPOSTGRES_PASSWORD=secret\nPOSTGRES_DB=shop\n",
+			dump:    "docker container exec buffalodb pg_dump -U buffalo -d shop --clean --if-exists --no-owner",
+			restore: "docker container exec -i buffalodb psql -q -v ON_ERROR_STOP=1 -U buffalo -d shop",
+		},
+		{
+			engine:  "mysql",
+			stored:  "MYSQL_USER=buffalo\nMYSQL_PASSWORD=secret\nMYSQL_DATABASE=shop\n",
+			dump:    "docker container exec -e MYSQL_PWD=secret buffalodb mysqldump --single-transaction -u buffalo shop",
+			restore: "docker container exec -i -e MYSQL_PWD=secret buffalodb mysql -u buffalo shop",
+		},
+		{
+			engine:  "cockroach",
+			stored:  "BUFFALO_OCEAN_DATABASE=shop\nDATABASE_URL=cockroach://root@buffalodb:26257/shop?sslmode=disable\n",
+			dump:    "docker container exec buffalodb ./cockroach dump shop --insecure",
+			restore: "docker container exec -i buffalodb ./cockroach sql --insecure -d shop",
+		},
+		{
+			engine:  "sqlite",
+			stored:  "BUFFALO_OCEAN_DATABASE=shop\nDATABASE_URL=sqlite3:///data/shop.sqlite\n",
+			dump:    "cat /root/db_volume/shop.sqlite",
+			restore: "cat > /root/db_volume/shop.sqlite",
+		},
+		// Servers set up before the name was stored fall back to the
+		// default name.
+		{
+			engine:  "cockroach",
+			stored:  "DATABASE_URL=cockroach://root@buffalodb:26257/buffalo_production?sslmode=disable\n",
+			dump:    "docker container exec buffalodb ./cockroach dump buffalo_production --insecure",
+			restore: "docker container exec -i buffalodb ./cockroach sql --insecure -d buffalo_production",
+		},
+		{
+			engine:  "sqlite",
+			stored:  "DATABASE_URL=sqlite3:///data/buffalo_production.sqlite\n",
+			dump:    "cat /root/db_volume/buffalo_production.sqlite",
+			restore: "cat > /root/db_volume/buffalo_production.sqlite",
+		},
+	}
+
+	for _, tt := range tests {
+		r := recordInto(t)
+		r.Responses[fmt.Sprintf("bash -c \"cat %s", remoteDBEnvFile)] = tt.stored
+
+		p := Project{Environment: "production", Database: tt.engine}
+		e, err := p.backupEngine()
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := ensureDBCredentials(p, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := e.Dump(c); got != tt.dump {
+			t.Errorf("%s dump:\n got %s\nwant %s", tt.engine, got, tt.dump)
+		}
+		if got := e.Restore(c); got != tt.restore {
+			t.Errorf("%s restore:\n got %s\nwant %s", tt.engine, got, tt.restore)
+		}
+	}
+}
+
+func TestGeneratedCredentialsStoreTheName(t *testing.T) {
+	for _, n := range []string{"postgres", "mysql", "cockroach", "sqlite"} {
+		r := recordInto(t)
+		r.Responses[fmt.Sprintf("bash -c \"cat %s", remoteDBEnvFile)] = "#missing"
+
+		p := Project{Environment: "production", Database: n, DatabaseName: "shop"}
+		c, err := ensureDBCredentials(p, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		e, _ := p.dbEngine()
+		stored := e.envList(c).envFile()
+		r.Responses[fmt.Sprintf("bash -c \"cat %s", remoteDBEnvFile)] = stored
+		back, ok, err := readDBCredentials(e)
+		if err != nil || !ok {
+			t.Fatalf("%s: could not read back %q: %v", n, stored, err)
+		}
+		if back.Name != "shop" {
+			t.Errorf("%s: read back name %q from %q, want shop", n, back.Name, stored)
+		}
+	}
+}
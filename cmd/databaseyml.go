@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// databaseYMLPaths are the places a Buffalo app keeps its database config.
+var databaseYMLPaths = []string{"database.yml", "config/database.yml"}
+
+// dialectEngines maps the dialects pop understands to our database engines.
+var dialectEngines = map[string]string{
+	"postgres":    "postgres",
+	"postgresql":  "postgres",
+	"mysql":       "mysql",
+	"cockroach":   "cockroach",
+	"cockroachdb": "cockroach",
+	"sqlite":      "sqlite",
+	"sqlite3":     "sqlite",
+}
+
+// dbDetails is a single environment of a database.yml file.
+type dbDetails struct {
+	Dialect  string            `yaml:"dialect"`
+	Database string            `yaml:"database"`
+	URL      string            `yaml:"url"`
+	Options  map[string]string `yaml:"options"`
+}
+
+// parseDatabaseYML reads the connection details of every environment. The
+// file is a template, env lookups resolve to their defaults so settings of
+// the local machine never end up on the server. Urls are left as they are,
+// only the environment being deployed has to have a valid one.
+func parseDatabaseYML(b []byte) (map[string]dbDetails, error) {
+	t, err := template.New("database.yml").Funcs(template.FuncMap{
+		"envOr": func(k, def string) string { return def },
+		"env":   func(k string) string { return "" },
+	}).Parse(string(b))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var bb bytes.Buffer
+	if err := t.Execute(&bb, nil); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	d := map[string]dbDetails{}
+	if err := yaml.Unmarshal(bb.Bytes(), &d); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return d, nil
+}
+
+// fromURL fills in the dialect, database and options from the url of the
+// environment, when it has one.
+func (d *dbDetails) fromURL() error {
+	if d.URL == "" {
+		return nil
+	}
+
+	u, err := parseDBURL(d.URL)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if d.Dialect == "" {
+		d.Dialect = u.Scheme
+	}
+	if d.Database == "" {
+		d.Database = strings.TrimPrefix(u.Path, "/")
+	}
+	for k, v := range u.Query() {
+		if d.Options == nil {
+			d.Options = map[string]string{}
+		}
+		if _, ok := d.Options[k]; !ok && len(v) > 0 {
+			d.Options[k] = v[0]
+		}
+	}
+	return nil
+}
+
+// parseDBURL parses a pop connection url, which for MySQL wraps the host in
+// parentheses as in mysql://user:pass@(host:3306)/db.
+func parseDBURL(s string) (*url.URL, error) {
+	if i := strings.Index(s, "://"); i >= 0 {
+		rest := s[i+3:]
+		j := strings.Index(rest, "/")
+		if j < 0 {
+			j = len(rest)
+		}
+		host := strings.NewReplacer("(", "", ")", "").Replace(rest[:j])
+		s = s[:i+3] + host + rest[j:]
+	}
+	return url.Parse(s)
+}
+
+// loadDatabaseYML provisions the database from the project's database.yml
+// for the chosen environment. A database given explicitly keeps precedence.
+func (p *Project) loadDatabaseYML() error {
+	defer func() {
+		if p.Database == "" {
+			p.Database = defaultDatabase
+		}
+	}()
+
+	var b []byte
+	var f string
+	for _, f = range databaseYMLPaths {
+		var err error
+		if b, err = ioutil.ReadFile(f); err == nil {
+			break
+		}
+		if !os.IsNotExist(err) {
+			return errors.WithStack(err)
+		}
+	}
+	if b == nil {
+		return nil
+	}
+
+	all, err := parseDatabaseYML(b)
+	if err != nil {
+		return errors.Wrapf(err, "could not parse %s", f)
+	}
+	d, ok := all[p.Environment]
+	if !ok {
+		color.Yellow("\n%s has no %s environment, using the defaults", f, p.Environment)
+		return nil
+	}
+	if err := d.fromURL(); err != nil {
+		return errors.Wrapf(err, "invalid url for %s in %s", p.Environment, f)
+	}
+
+	if p.Database == "" {
+		e, ok := dialectEngines[strings.ToLower(d.Dialect)]
+		if !ok {
+			return errors.Errorf("the %s dialect in %s is not supported", d.Dialect, f)
+		}
+		p.Database = e
+	}
+	if p.DatabaseName == "" && d.Database != "" {
+		p.DatabaseName = strings.TrimSuffix(path.Base(d.Database), path.Ext(d.Database))
+	}
+	if len(p.DatabaseOptions) == 0 {
+		p.DatabaseOptions = d.Options
+	}
+
+	color.Blue("\n==> Using the %s database %s from %s", p.Database, p.databaseName(), f)
+	return nil
+}
+
+// withURLOptions sets the given options as query parameters of u. Only the
+// query is rewritten so the parenthesised MySQL host survives.
+func withURLOptions(u string, opts map[string]string) string {
+	if len(opts) == 0 || u == "" {
+		return u
+	}
+
+	base, raw := splitQuery(u)
+	q, err := url.ParseQuery(raw)
+	if err != nil {
+		return u
+	}
+	for k, v := range opts {
+		q.Set(k, v)
+	}
+	return base + "?" + q.Encode()
+}
+
+// urlOptions returns the query parameters of u, so options stored with the
+// credentials survive them being rewritten.
+func urlOptions(u string) map[string]string {
+	_, raw := splitQuery(u)
+	q, err := url.ParseQuery(raw)
+	if err != nil || raw == "" {
+		return nil
+	}
+	opts := map[string]string{}
+	for k, v := range q {
+		if len(v) > 0 {
+			opts[k] = v[0]
+		}
+	}
+	return opts
+}
+
+// splitQuery splits u into everything before the query and the raw query.
+func splitQuery(u string) (string, string) {
+	if i := strings.Index(u, "?"); i >= 0 {
+		return u[:i], u[i+1:]
+	}
+	return u, ""
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestLoadDatabaseYMLMySQLURL(t *testing.T) {
+	recordInto(t)
+	yml := `development:
+  url: {{envOr "DATABASE_URL" "mysql://root:root@(localhost:3306)/shop_development"}}
+test:
+  url: mysql://root:root@(127.0.0.1:3306)/shop_test?parseTime=true
+production:
+  url: {{envOr "DATABASE_URL" "mysql://shop:secret@(db:3306)/shop_production?charset=utf8mb4"}}
+`
+	if err := ioutil.WriteFile("database.yml", []byte(yml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := Project{Environment: "production"}
+	if err := p.loadDatabaseYML(); err != nil {
+		t.Fatal(err)
+	}
+	if p.Database != "mysql" || p.DatabaseName != "shop_production" || p.DatabaseOptions["charset"] != "utf8mb4" {
+		t.Errorf("got %s %s %v, want mysql shop_production charset=utf8mb4", p.Database, p.DatabaseName, p.DatabaseOptions)
+	}
+}
+
+func TestLoadDatabaseYMLIgnoresOtherEnvironments(t *testing.T) {
+	recordInto(t)
+	yml := `test:
+  url: "::not a url"
+production:
+  dialect: postgres
+  database: shop_production
+`
+	if err := ioutil.WriteFile("database.yml", []byte(yml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := Project{Environment: "production"}
+	if err := p.loadDatabaseYML(); err != nil {
+		t.Fatal(err)
+	}
+	if p.Database != "postgres" || p.DatabaseName != "shop_production" {
+		t.Errorf("got %s %s, want postgres shop_production", p.Database, p.DatabaseName)
+	}
+}
+
+func TestWithURLOptionsKeepsMySQLHost(t *testing.T) {
+	u := "mysql://buffalo:secret@(buffalodb:3306)/shop?parseTime=true"
+	got := withURLOptions(u, map[string]string{"charset": "utf8mb4"})
+	want := "mysql://buffalo:secret@(buffalodb:3306)/shop?charset=utf8mb4&parseTime=true"
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if o := urlOptions(got); o["charset"] != "utf8mb4" || o["parseTime"] != "true" {
+		t.Errorf("urlOptions(%s) = %v", got, o)
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	emoji "gopkg.in/kyokomi/emoji.v1"
+)
+
+// dbCmd represents the db command
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the database of the deployed application",
+}
+
+var dbRotatePasswordCmd = &cobra.Command{
+	Use:   "rotate-password",
+	Short: "Generate a new database password and apply it to the database and web containers",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return dbProject.rotateDBPassword()
+	},
+}
+
+var dbProject = Project{}
+
+func init() {
+	dbCmd.PersistentFlags().StringVarP(&dbProject.AppName, "app-name", "a", "", "The name for the application")
+	dbCmd.PersistentFlags().StringVarP(&dbProject.Environment, "environment", "e", "production", "Setting for the GO_ENV variable")
+	dbCmd.PersistentFlags().BoolVar(&dbProject.SkipSSL, "skip-ssl", false, "The SSL setup step was skipped for this application")
+	addDatabaseFlag(dbCmd.PersistentFlags(), &dbProject)
+	dbCmd.AddCommand(dbRotatePasswordCmd)
+	oceanCmd.AddCommand(dbCmd)
+}
+
+func (p Project) rotateDBPassword() error {
+	p.connect()
+	magenta := color.New(color.FgMagenta).SprintFunc()
+
+	e, err := p.dbEngine()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if e.Rotate == nil {
+		return errors.Errorf("rotating the password is not supported for the %s database", e.Name)
+	}
+
+	c, err := ensureDBCredentials(p, false)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	pw, err := randomString(32)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	color.Blue("\n==> Updating the password of %s", c.User)
+	if err := remoteCmd(e.Rotate(c, pw)); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Password = pw
+	if err := writeDBCredentials(e, c); err != nil {
+		return errors.Wrap(err, "the database password was changed but could not be stored, run rotate-password again")
+	}
+
+	if err := restartWebContainer(p); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if _, err := emoji.Printf("\n========= :key: %s :key: =========\n", magenta("DATABASE PASSWORD ROTATED")); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// healthyServer answers the checks deploy makes like a server that setup
+// left running.
+func healthyServer(r *recordingExecutor) {
+	r.Responses["docker container inspect --format '{{.Name}}"] = fmt.Sprintf("/%s running\n/%s running\n", webContainer, dbContainer)
+	r.Responses["docker container inspect --format '{{.Config.Image}}'"] = releaseImage("1111111-20190101000000")
+	r.Responses["docker network inspect --format"] = webNetwork
+	r.Responses["bash -c \"if [ -f /etc/caddy/Caddyfile ]"] = "active"
+	r.Responses[fmt.Sprintf("docker container port %s", webContainer)] = "0.0.0.0:3000"
+	r.Responses["git -C buffaloproject rev-parse --short HEAD"] = "abc1234"
+	r.Responses["git -C buffaloproject rev-parse HEAD"] = "abc1234def5678"
+	r.Responses["curl -s -o /dev/null"] = "200"
+	r.Responses[fmt.Sprintf("bash -c \"cat %s", remoteDBEnvFile)] = "POSTGRES_USER=buffalo\nPOSTGRES_PASSWORD=secret\nPOSTGRES_DB=demo_production\n"
+}
+
+func TestDeployProcess(t *testing.T) {
+	r := recordInto(t)
+	healthyServer(r)
+	r.Responses[fmt.Sprintf("docker image ls %s", webImage)] = "abc1234-20190102030405\n1111111-20190101000000\n0000000-20181231000000\nlatest\n"
+
+	prevDeploy := deploy
+	defer func() { deploy = prevDeploy }()
+	deploy = Project{
+		AppName:     "demo",
+		Branch:      "master",
+		Environment: "production",
+		Database:    "postgres",
+		Keep:        2,
+		Migrate:     true,
+	}
+	projectName, serverName = "demo", "demo-production"
+
+	if err := deployProcess(deploy); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, r, "deploy")
+}
+
+func TestDeployProcessSkipSSL(t *testing.T) {
+	r := recordInto(t)
+	healthyServer(r)
+
+	prevDeploy := deploy
+	defer func() { deploy = prevDeploy }()
+	deploy = Project{
+		AppName:     "demo",
+		Branch:      "master",
+		Environment: "production",
+		Database:    "postgres",
+		SkipSSL:     true,
+		Tag:         "v1.0.0",
+	}
+	projectName, serverName = "demo", "demo-production"
+
+	if err := deployProcess(deploy); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, r, "deploy_skip_ssl")
+}
+
+func TestDeployProcessPruneFailureIsAWarning(t *testing.T) {
+	r := recordInto(t)
+	healthyServer(r)
+	r.Responses[fmt.Sprintf("docker image ls %s", webImage)] = "abc1234-20190102030405\n1111111-20190101000000\n0000000-20181231000000\n"
+	r.Failures["docker image rm"] = errors.New("image is in use")
+
+	prevDeploy := deploy
+	defer func() { deploy = prevDeploy }()
+	deploy = Project{AppName: "demo", Branch: "master", Environment: "production", Database: "postgres", Keep: 2}
+	projectName, serverName = "demo", "demo-production"
+
+	if err := deployProcess(deploy); err != nil {
+		t.Fatalf("the deploy failed on pruning: %s", err)
+	}
+	if last := r.Commands[len(r.Commands)-1]; !strings.Contains(last, `"outcome":"success"`) {
+		t.Errorf("the ledger did not record a success: %s", last)
+	}
+}
+
+func TestDeployProcessRepairsMissingProxy(t *testing.T) {
+	r := recordInto(t)
+	healthyServer(r)
+	r.Responses["bash -c \"if [ -f /etc/caddy/Caddyfile ]"] = "missing"
+	r.Responses[fmt.Sprintf("docker container port %s", webContainer)] = "0.0.0.0:3001"
+
+	prevDeploy, prevRepair := deploy, deployRepair
+	defer func() { deploy, deployRepair = prevDeploy, prevRepair }()
+	deployRepair = true
+	deploy = Project{
+		AppName:     "demo",
+		Branch:      "master",
+		Environment: "production",
+		Database:    "postgres",
+		Domain:      "demo.example.com",
+		Email:       "ops@example.com",
+	}
+	projectName, serverName = "demo", "demo-production"
+
+	if err := deployProcess(deploy); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, r, "deploy_repair_proxy")
+}
+
+func TestDeployProcessRepairNeedsTheSSLSettings(t *testing.T) {
+	r := recordInto(t)
+	healthyServer(r)
+	r.Responses["bash -c \"if [ -f /etc/caddy/Caddyfile ]"] = "missing"
+
+	prevDeploy, prevRepair := deploy, deployRepair
+	defer func() { deploy, deployRepair = prevDeploy, prevRepair }()
+	deployRepair = true
+	deploy = Project{AppName: "demo", Branch: "master", Environment: "production", Database: "postgres"}
+	projectName, serverName = "demo", "demo-production"
+
+	err := deployProcess(deploy)
+	if err == nil || !strings.Contains(err.Error(), "--domain") || !strings.Contains(err.Error(), "--email") {
+		t.Fatalf("got %v, want the missing domain and email", err)
+	}
+	for _, c := range r.Commands {
+		if strings.Contains(c, "caddy") && !strings.HasPrefix(c, "bash -c \"if [ -f /etc/caddy/Caddyfile ]") {
+			t.Errorf("ran %q", c)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/wolves/buffalo-ocean/digitalocean"
+)
+
+// apiURL points the DigitalOcean client at a stand-in for the API.
+var apiURL string
+
+// dropletTimeout bounds how long setup waits for a new droplet to boot.
+const dropletTimeout = 5 * time.Minute
+
+// localKeyPath is where the key used to reach a droplet created through the
+// API is kept.
+func localKeyPath(name string) (string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return filepath.Join(u.HomeDir, ".buffalo-ocean", name, "id_rsa"), nil
+}
+
+// ensureLocalKey generates an SSH key pair at path unless one exists.
+func ensureLocalKey(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.WithStack(err)
+	}
+	return runLocal("ssh-keygen", []string{"-q", "-t", "rsa", "-b", "4096", "-N", "", "-C", serverName, "-f", path}, nil, os.Stdout, os.Stderr)
+}
+
+func newDOClient(token string) (*digitalocean.Client, error) {
+	return digitalocean.NewClient(token, apiURL)
+}
+
+// apiProvider creates the droplet through the DigitalOcean API and reaches
+// it over plain SSH.
+type apiProvider struct{}
+
+func (a apiProvider) Prepare(p *Project) error {
+	if err := p.validateDroplet(); err != nil {
+		return errors.WithStack(err)
+	}
+	p.Key = providerToken(p.Key, "DigitalOcean", "https://cloud.digitalocean.com/settings/api/tokens/new")
+	if !resumingServer(a, *p) {
+		p.printDropletPlan()
+	}
+	return nil
+}
+
+// droplet looks up the droplet of the server.
+func (a apiProvider) droplet(p Project) (digitalocean.Droplet, bool, error) {
+	c, err := newDOClient(p.Key)
+	if err != nil {
+		return digitalocean.Droplet{}, false, errors.WithStack(err)
+	}
+	return c.FindDroplet(context.Background(), serverName)
+}
+
+func (a apiProvider) Exists(p Project) (bool, error) {
+	_, ok, err := a.droplet(p)
+	return ok, errors.WithStack(err)
+}
+
+func (a apiProvider) Create(p *Project) error {
+	return createDroplet(p)
+}
+
+// Destroy deletes the droplet, the SSH key uploaded for it and the local
+// copy of that key.
+func (a apiProvider) Destroy(p Project) error {
+	c, err := newDOClient(p.Key)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	d, ok, err := a.droplet(p)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if ok {
+		if err := c.DeleteDroplet(context.Background(), d.ID); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	key, err := localKeyPath(serverName)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if pub, err := ioutil.ReadFile(key + ".pub"); err == nil {
+		fp, err := digitalocean.Fingerprint(string(pub))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if err := c.DeleteSSHKey(context.Background(), fp); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return errors.WithStack(os.RemoveAll(filepath.Dir(key)))
+}
+
+func (a apiProvider) Start(p Project) error {
+	return a.action(p, (*digitalocean.Client).PowerOn)
+}
+
+func (a apiProvider) Stop(p Project) error {
+	return a.action(p, (*digitalocean.Client).Shutdown)
+}
+
+func (a apiProvider) Restart(p Project) error {
+	return a.action(p, (*digitalocean.Client).Reboot)
+}
+
+// action runs a power action on the droplet and, unless it was switched
+// off, waits until it accepts SSH connections again.
+func (a apiProvider) action(p Project, run func(*digitalocean.Client, context.Context, int) error) error {
+	d, ok, err := a.droplet(p)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if !ok {
+		return errors.Errorf("there is no droplet named %s", serverName)
+	}
+	c, err := newDOClient(p.Key)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dropletTimeout)
+	defer cancel()
+	if err := run(c, ctx, d.ID); err != nil {
+		return errors.WithStack(err)
+	}
+	if s, _ := a.Status(p); s == "Stopped" {
+		return nil
+	}
+	return waitForSSH()
+}
+
+func (a apiProvider) Status(p Project) (string, error) {
+	d, ok, err := a.droplet(p)
+	if err != nil {
+		return "Error", errors.WithStack(err)
+	}
+	if !ok {
+		return "Does not exist", nil
+	}
+	switch d.Status {
+	case "active":
+		return "Running", nil
+	case "off":
+		return "Stopped", nil
+	case "new":
+		return "Starting", nil
+	}
+	return d.Status, nil
+}
+
+func (a apiProvider) IP(p Project) (string, error) {
+	d, ok, err := a.droplet(p)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if !ok {
+		return "", errors.Errorf("there is no droplet named %s", serverName)
+	}
+	return d.IPv4, nil
+}
+
+// createDroplet creates the droplet for p through the DigitalOcean API,
+// or picks up the one an interrupted setup left behind, and points the
+// executor at it over SSH.
+func createDroplet(p *Project) error {
+	green := color.New(color.FgGreen).SprintFunc()
+
+	key, err := localKeyPath(serverName)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := ensureLocalKey(key); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c, err := newDOClient(p.Key)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dropletTimeout)
+	defer cancel()
+
+	d, ok, err := c.FindDroplet(ctx, serverName)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if ok {
+		color.Yellow("\n==> The droplet %s exists already, finishing its setup", serverName)
+	} else {
+		color.Blue("\n==> Creating droplet: %s\n", green(serverName))
+		if d, err = launchDroplet(ctx, c, *p, key); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	color.Blue("\n==> Waiting for droplet %d to become active", d.ID)
+	if d, err = c.WaitForDroplet(ctx, d.ID); err != nil {
+		return errors.WithStack(err)
+	}
+
+	p.SSHHost = fmt.Sprintf("root@%s", d.IPv4)
+	p.SSHKey = key
+	sshHost, sshKey = p.SSHHost, p.SSHKey
+	executor = newSSHExecutor(sshHost, sshKey)
+	// Saved right away so a resumed setup reaches the droplet over SSH.
+	if err := writeConfig(*p); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return installDocker()
+}
+
+// launchDroplet uploads the public half of key and creates a droplet for p
+// that root can log in to with it, tagged with the server name.
+func launchDroplet(ctx context.Context, c *digitalocean.Client, p Project, key string) (digitalocean.Droplet, error) {
+	pub, err := ioutil.ReadFile(key + ".pub")
+	if err != nil {
+		return digitalocean.Droplet{}, errors.WithStack(err)
+	}
+	fp, err := c.UploadSSHKey(ctx, serverName, string(pub))
+	if err != nil {
+		return digitalocean.Droplet{}, errors.WithStack(err)
+	}
+
+	d, err := c.CreateDroplet(ctx, digitalocean.DropletRequest{
+		Name:              serverName,
+		Region:            p.Region,
+		Size:              p.Size,
+		Image:             p.Image,
+		IPv6:              p.IPv6,
+		PrivateNetworking: p.PrivateNetworking,
+		Monitoring:        p.Monitoring,
+		Backups:           p.Backups,
+		SSHKeyFingerprint: fp,
+		// Tagged as it is created, an untagged droplet would never be
+		// found again.
+		Tags: []string{serverName},
+	})
+	return d, errors.WithStack(err)
+}
+
+// installDocker waits for SSH to come up on a fresh server and installs
+// docker, which docker-machine would otherwise have done. It does nothing
+// more when docker is there already, so it can be run again.
+func installDocker() error {
+	color.Blue("\n==> Installing Docker")
+	if err := waitForSSH(); err != nil {
+		return errors.WithStack(err)
+	}
+	return remoteCmd("bash -c \"command -v docker > /dev/null || curl -fsSL https://get.docker.com | sh\"")
+}
+
+// waitForSSH waits for a server that is booting to accept SSH connections.
+func waitForSSH() error {
+	for i := 0; ; i++ {
+		if _, err := executor.Status(); err == nil {
+			return nil
+		} else if i == 30 {
+			return errors.Wrap(err, "the server never accepted SSH connections")
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
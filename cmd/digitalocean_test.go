@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/wolves/buffalo-ocean/digitalocean"
+)
+
+func TestLaunchDropletTagsItWithTheServerName(t *testing.T) {
+	var tags []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + r.URL.Path {
+		case "POST /v2/account/keys":
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"ssh_key":{"id":1,"fingerprint":"aa:bb"}}`)
+		case "POST /v2/droplets":
+			var body struct{ Tags []string }
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Error(err)
+			}
+			tags = body.Tags
+			w.WriteHeader(http.StatusAccepted)
+			fmt.Fprint(w, `{"droplet":{"id":42,"name":"demo-production","status":"new"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"id":"not_found","message":"not found"}`)
+		}
+	}))
+	defer s.Close()
+
+	prevURL, prevServer := apiURL, serverName
+	defer func() { apiURL, serverName = prevURL, prevServer }()
+	apiURL, serverName = s.URL+"/", "demo-production"
+
+	key := filepath.Join(t.TempDir(), "id_rsa")
+	if err := ioutil.WriteFile(key+".pub", []byte("ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAAAgQC7 demo-production\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := newDOClient("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := launchDroplet(context.Background(), c, Project{}, key); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{digitalocean.Tag, "demo-production"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("created the droplet with tags %q, want %q", tags, want)
+	}
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+const (
+	defaultDropletSize   = "s-1vcpu-1gb"
+	defaultDropletRegion = "nyc3"
+	defaultDropletImage  = "ubuntu-16-04-x64"
+)
+
+// dropletSize is a DigitalOcean standard droplet plan.
+type dropletSize struct {
+	VCPUs  int
+	Memory string
+	Disk   int
+	// Price is the monthly price in US dollars.
+	Price float64
+}
+
+// dropletSizes are the standard droplet plans with their list prices.
+var dropletSizes = map[string]dropletSize{
+	"s-1vcpu-1gb":    {1, "1GB", 25, 5},
+	"s-1vcpu-2gb":    {1, "2GB", 50, 10},
+	"s-1vcpu-3gb":    {1, "3GB", 60, 15},
+	"s-2vcpu-2gb":    {2, "2GB", 60, 15},
+	"s-3vcpu-1gb":    {3, "1GB", 60, 15},
+	"s-2vcpu-4gb":    {2, "4GB", 80, 20},
+	"s-4vcpu-8gb":    {4, "8GB", 160, 40},
+	"s-6vcpu-16gb":   {6, "16GB", 320, 80},
+	"s-8vcpu-32gb":   {8, "32GB", 640, 160},
+	"s-12vcpu-48gb":  {12, "48GB", 960, 240},
+	"s-16vcpu-64gb":  {16, "64GB", 1280, 320},
+	"s-20vcpu-96gb":  {20, "96GB", 1920, 480},
+	"s-24vcpu-128gb": {24, "128GB", 2560, 640},
+	"s-32vcpu-192gb": {32, "192GB", 3840, 960},
+}
+
+// dropletRegions are the regions droplets can be created in.
+var dropletRegions = map[string]string{
+	"nyc1": "New York 1",
+	"nyc3": "New York 3",
+	"sfo2": "San Francisco 2",
+	"tor1": "Toronto 1",
+	"ams3": "Amsterdam 3",
+	"fra1": "Frankfurt 1",
+	"lon1": "London 1",
+	"sgp1": "Singapore 1",
+	"blr1": "Bangalore 1",
+}
+
+// dropletImages are the distributions docker-machine can provision.
+var dropletImages = []string{"ubuntu-16-04-x64", "ubuntu-18-04-x64", "debian-9-x64"}
+
+// backupsPriceRatio is the share of the droplet price charged for weekly
+// DigitalOcean backups.
+const backupsPriceRatio = 0.2
+
+// addDropletFlags adds the flags choosing the droplet created by setup.
+func addDropletFlags(fs *pflag.FlagSet, p *Project) {
+	fs.StringVar(&p.Size, "size", defaultDropletSize, "The DigitalOcean droplet size")
+	fs.StringVar(&p.Region, "region", defaultDropletRegion, "The DigitalOcean region the droplet is created in")
+	fs.StringVar(&p.Image, "image", defaultDropletImage, "The DigitalOcean image the droplet is created from")
+	fs.BoolVar(&p.IPv6, "ipv6", false, "Enable IPv6 on the droplet")
+	fs.BoolVar(&p.PrivateNetworking, "private-networking", false, "Enable private networking on the droplet")
+	fs.BoolVar(&p.Monitoring, "monitoring", false, "Install the DigitalOcean monitoring agent")
+	fs.BoolVar(&p.Backups, "backups", false, "Enable weekly DigitalOcean backups of the droplet")
+}
+
+func sortedKeys(m map[string]string) []string {
+	var k []string
+	for n := range m {
+		k = append(k, n)
+	}
+	sort.Strings(k)
+	return k
+}
+
+// validateDroplet checks the droplet options of p against the known sizes,
+// regions and images.
+func (p Project) validateDroplet() error {
+	if _, ok := dropletSizes[p.Size]; !ok {
+		var n []string
+		for s := range dropletSizes {
+			n = append(n, s)
+		}
+		sort.Strings(n)
+		return errors.Errorf("%q is not a known droplet size, use one of: %s", p.Size, strings.Join(n, ", "))
+	}
+	if _, ok := dropletRegions[p.Region]; !ok {
+		return errors.Errorf("%q is not a known region, use one of: %s", p.Region, strings.Join(sortedKeys(dropletRegions), ", "))
+	}
+	for _, i := range dropletImages {
+		if i == p.Image {
+			return nil
+		}
+	}
+	return errors.Errorf("%q is not a supported image, use one of: %s", p.Image, strings.Join(dropletImages, ", "))
+}
+
+// monthlyCost estimates the monthly price of the droplet of p.
+func (p Project) monthlyCost() float64 {
+	c := dropletSizes[p.Size].Price
+	if p.Backups {
+		c += c * backupsPriceRatio
+	}
+	return c
+}
+
+// printDropletPlan shows what is about to be created and what it costs.
+func (p Project) printDropletPlan() {
+	s := dropletSizes[p.Size]
+	color.Blue("\n==> DROPLET: %s in %s (%s)", p.Size, dropletRegions[p.Region], p.Region)
+	fmt.Printf("    %d vCPU, %s memory, %dGB disk, %s\n", s.VCPUs, s.Memory, s.Disk, p.Image)
+
+	var extras []string
+	for _, o := range []struct {
+		on   bool
+		name string
+	}{{p.IPv6, "IPv6"}, {p.PrivateNetworking, "private networking"}, {p.Monitoring, "monitoring"}, {p.Backups, "backups"}} {
+		if o.on {
+			extras = append(extras, o.name)
+		}
+	}
+	if len(extras) > 0 {
+		fmt.Printf("    with %s\n", strings.Join(extras, ", "))
+	}
+	color.Yellow("    Estimated cost: $%.2f/month", p.monthlyCost())
+}
+
+// dropletFlags are the docker-machine create flags for the droplet of p.
+func (p Project) dropletFlags() []string {
+	f := []string{
+		fmt.Sprintf("--digitalocean-size=%s", p.Size),
+		fmt.Sprintf("--digitalocean-region=%s", p.Region),
+		fmt.Sprintf("--digitalocean-image=%s", p.Image),
+	}
+	if p.IPv6 {
+		f = append(f, "--digitalocean-ipv6")
+	}
+	if p.PrivateNetworking {
+		f = append(f, "--digitalocean-private-networking")
+	}
+	if p.Monitoring {
+		f = append(f, "--digitalocean-monitoring")
+	}
+	if p.Backups {
+		f = append(f, "--digitalocean-backups")
+	}
+	return f
+}
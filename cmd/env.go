@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// envCmd represents the env command
+var envCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Manage the env vars of the deployed application",
+}
+
+var envListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the env vars set on the server",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		envProject.connect()
+		l, err := readRemoteEnv()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		sorted := append(envList{}, l...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].Key < sorted[j].Key })
+		for _, v := range sorted {
+			val := v.Value
+			if !envShowSecrets && isSecretEnvKey(v.Key) {
+				val = maskEnvValue(val)
+			}
+			fmt.Printf("%s=%s\n", color.GreenString(v.Key), val)
+		}
+		return nil
+	},
+}
+
+var envGetCmd = &cobra.Command{
+	Use:   "get KEY",
+	Short: "Print the value of a single env var",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		envProject.connect()
+		l, err := readRemoteEnv()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		v, ok := l.get(args[0])
+		if !ok {
+			return errors.Errorf("%s is not set", args[0])
+		}
+		fmt.Println(v)
+		return nil
+	},
+}
+
+var envSetCmd = &cobra.Command{
+	Use:   "set KEY=VALUE [KEY=VALUE...]",
+	Short: "Set one or more env vars",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		envProject.connect()
+		l, err := readRemoteEnv()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		for _, a := range args {
+			v, err := parseEnvPair(a)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			l = l.set(v.Key, v.Value)
+			color.Blue("==> SET: %s", v.Key)
+		}
+		return updateRemoteEnv(l)
+	},
+}
+
+var envUnsetCmd = &cobra.Command{
+	Use:   "unset KEY [KEY...]",
+	Short: "Remove one or more env vars",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		envProject.connect()
+		l, err := readRemoteEnv()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		for _, k := range args {
+			var ok bool
+			if l, ok = l.unset(k); !ok {
+				return errors.Errorf("%s is not set", k)
+			}
+			color.Blue("==> UNSET: %s", k)
+		}
+		return updateRemoteEnv(l)
+	},
+}
+
+var envPushCmd = &cobra.Command{
+	Use:   "push [FILE]",
+	Short: "Merge a local .env file into the env vars on the server",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := ".env"
+		if len(args) > 0 {
+			f = args[0]
+		}
+
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		local, err := parseDotEnv(string(b))
+		if err != nil {
+			return errors.Wrapf(err, "could not parse %s", f)
+		}
+
+		envProject.connect()
+		l, err := readRemoteEnv()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		for _, v := range local {
+			l = l.set(v.Key, v.Value)
+		}
+		color.Blue("==> PUSHING: %d env vars from %s", len(local), f)
+		return updateRemoteEnv(l)
+	},
+}
+
+var envPullCmd = &cobra.Command{
+	Use:   "pull [FILE]",
+	Short: "Write the env vars on the server to a local .env file",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := ".env"
+		if len(args) > 0 {
+			f = args[0]
+		}
+
+		envProject.connect()
+		return pullEnv(f)
+	},
+}
+
+var envProject = Project{}
+var envShowSecrets bool
+var envRestart bool
+var envForce bool
+
+func init() {
+	envCmd.PersistentFlags().StringVarP(&envProject.AppName, "app-name", "a", "", "The name for the application")
+	envCmd.PersistentFlags().StringVarP(&envProject.Environment, "environment", "e", "production", "Setting for the GO_ENV variable")
+	envCmd.PersistentFlags().BoolVar(&envProject.SkipSSL, "skip-ssl", false, "The SSL setup step was skipped for this application")
+	addDatabaseFlag(envCmd.PersistentFlags(), &envProject)
+	envListCmd.Flags().BoolVarP(&envShowSecrets, "show-secrets", "s", false, "Print secret looking values instead of masking them")
+	for _, c := range []*cobra.Command{envSetCmd, envUnsetCmd, envPushCmd} {
+		c.Flags().BoolVarP(&envRestart, "restart", "r", false, "Restart the web container so the change takes effect")
+	}
+
+	envPullCmd.Flags().BoolVarP(&envForce, "force", "f", false, "Overwrite the file when it exists")
+
+	envCmd.AddCommand(envListCmd, envGetCmd, envSetCmd, envUnsetCmd, envPushCmd, envPullCmd)
+	oceanCmd.AddCommand(envCmd)
+}
+
+// readRemoteEnv loads the env file stored on the server.
+func readRemoteEnv() (envList, error) {
+	out, err := remoteOutput(fmt.Sprintf("bash -c \"cat %s 2>/dev/null || true\"", remoteEnvFile))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return parseEnvFile(out)
+}
+
+// pullEnv writes the env vars on the server to the local file f. An
+// existing file is only overwritten with --force or when confirmed.
+func pullEnv(f string) error {
+	if _, err := os.Stat(f); err == nil && !envForce {
+		if nonInteractive {
+			return errors.Errorf("%s exists already, pass --force to overwrite it", f)
+		}
+		a := requestUserInput(fmt.Sprintf("%s exists already, overwrite it? [y/N]", f))
+		if !strings.HasPrefix(strings.ToLower(a), "y") {
+			return errors.Errorf("%s was left as it is, pass --force to overwrite it", f)
+		}
+	}
+
+	l, err := readRemoteEnv()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := ioutil.WriteFile(f, []byte(l.dotEnv()), 0600); err != nil {
+		return errors.WithStack(err)
+	}
+	color.Blue("==> PULLED: %d env vars into %s", len(l), f)
+	return nil
+}
+
+// writeRemoteEnv replaces the env file stored on the server with l.
+func writeRemoteEnv(l envList) error {
+	return writeRemoteFile(l.envFile(), remoteEnvFile)
+}
+
+func updateRemoteEnv(l envList) error {
+	if err := writeRemoteEnv(l); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if !envRestart {
+		color.Yellow("\nRestart the web container (--restart) or deploy for the change to take effect.")
+		return nil
+	}
+	return restartWebContainer(envProject)
+}
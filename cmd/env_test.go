@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestPullEnvKeepsAnExistingFile(t *testing.T) {
+	r := recordInto(t)
+	r.Responses[fmt.Sprintf("bash -c \"cat %s", remoteEnvFile)] = "SESSION_SECRET=from-the-server\n"
+
+	prevForce := envForce
+	defer func() { envForce = prevForce }()
+	envForce = false
+
+	f := filepath.Join(t.TempDir(), ".env")
+	if err := ioutil.WriteFile(f, []byte("LOCAL=1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pullEnv(f); err == nil {
+		t.Error("an existing file was overwritten without --force")
+	}
+	if b, _ := ioutil.ReadFile(f); string(b) != "LOCAL=1\n" {
+		t.Errorf("the file was changed to %q", b)
+	}
+
+	envForce = true
+	if err := pullEnv(f); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(f); string(b) != "SESSION_SECRET=from-the-server\n" {
+		t.Errorf("got %q with --force", b)
+	}
+}
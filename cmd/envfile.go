@@ -0,0 +1,259 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type envVar struct {
+	Key   string
+	Value string
+}
+
+// envList is an ordered set of env vars as stored in a docker --env-file.
+type envList []envVar
+
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var secretKeyParts = []string{"SECRET", "PASSWORD", "PASSWD", "TOKEN", "KEY", "PRIVATE", "CREDENTIAL", "DATABASE_URL", "DSN"}
+
+// parseEnvPair splits a KEY=VALUE argument.
+func parseEnvPair(s string) (envVar, error) {
+	i := strings.Index(s, "=")
+	if i <= 0 {
+		return envVar{}, errors.Errorf("%q is not in the form KEY=VALUE", s)
+	}
+
+	v := envVar{Key: strings.TrimSpace(s[:i]), Value: s[i+1:]}
+	if !envKeyPattern.MatchString(v.Key) {
+		return envVar{}, errors.Errorf("%q is not a valid env var name", v.Key)
+	}
+	if strings.ContainsAny(v.Value, "\r\n") {
+		return envVar{}, errors.Errorf("the value of %s can not contain line breaks", v.Key)
+	}
+	return v, nil
+}
+
+// parseEnvFile reads the docker --env-file format. Docker takes everything
+// after the first "=" literally, so no unquoting happens here.
+func parseEnvFile(s string) (envList, error) {
+	var l envList
+
+	sc := bufio.NewScanner(strings.NewReader(s))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		v, err := parseEnvPair(line)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		l = l.set(v.Key, v.Value)
+	}
+	return l, errors.WithStack(sc.Err())
+}
+
+// parseDotEnv reads a .env file, honouring "export" prefixes, comments and
+// single or double quoted values.
+func parseDotEnv(s string) (envList, error) {
+	var l envList
+
+	sc := bufio.NewScanner(strings.NewReader(s))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+		i := strings.Index(line, "=")
+		if i <= 0 {
+			return nil, errors.Errorf("line %d: %q is not in the form KEY=VALUE", n, line)
+		}
+		k := strings.TrimSpace(line[:i])
+		if !envKeyPattern.MatchString(k) {
+			return nil, errors.Errorf("line %d: %q is not a valid env var name", n, k)
+		}
+
+		v, err := unquoteEnvValue(strings.TrimSpace(line[i+1:]))
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", n)
+		}
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, errors.Errorf("line %d: the value of %s can not contain line breaks", n, k)
+		}
+		l = l.set(k, v)
+	}
+	return l, errors.WithStack(sc.Err())
+}
+
+func unquoteEnvValue(v string) (string, error) {
+	if v == "" {
+		return v, nil
+	}
+
+	switch v[0] {
+	case '\'':
+		end := strings.Index(v[1:], "'")
+		if end < 0 {
+			return "", errors.New("unterminated single quote")
+		}
+		return v[1 : end+1], nil
+	case '"':
+		var b strings.Builder
+		for i := 1; i < len(v); i++ {
+			switch c := v[i]; c {
+			case '"':
+				return b.String(), nil
+			case '\\':
+				if i+1 < len(v) {
+					i++
+					switch v[i] {
+					case 'n':
+						b.WriteByte('\n')
+					case 't':
+						b.WriteByte('\t')
+					default:
+						b.WriteByte(v[i])
+					}
+				}
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return "", errors.New("unterminated double quote")
+	}
+
+	if i := strings.Index(v, " #"); i >= 0 {
+		v = strings.TrimSpace(v[:i])
+	}
+	return v, nil
+}
+
+// splitEnvInput splits the env vars typed at the setup prompt. Pairs are
+// separated by whitespace and values may be quoted to keep their spaces,
+// eg. FOO=bar GREETING="hello world".
+func splitEnvInput(s string) (envList, error) {
+	var l envList
+	var word strings.Builder
+	var quote rune
+	inWord := false
+
+	flush := func() error {
+		if !inWord {
+			return nil
+		}
+		v, err := parseEnvPair(word.String())
+		if err != nil {
+			return err
+		}
+		l = l.set(v.Key, v.Value)
+		word.Reset()
+		inWord = false
+		return nil
+	}
+
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t':
+			if err := flush(); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.Errorf("unterminated %c quote", quote)
+	}
+	if err := flush(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return l, nil
+}
+
+func (l envList) get(key string) (string, bool) {
+	for _, v := range l {
+		if v.Key == key {
+			return v.Value, true
+		}
+	}
+	return "", false
+}
+
+func (l envList) set(key, value string) envList {
+	for i, v := range l {
+		if v.Key == key {
+			l[i].Value = value
+			return l
+		}
+	}
+	return append(l, envVar{Key: key, Value: value})
+}
+
+func (l envList) unset(key string) (envList, bool) {
+	for i, v := range l {
+		if v.Key == key {
+			return append(l[:i], l[i+1:]...), true
+		}
+	}
+	return l, false
+}
+
+// envFile renders the list in the docker --env-file format.
+func (l envList) envFile() string {
+	var b strings.Builder
+	for _, v := range l {
+		fmt.Fprintf(&b, "%s=%s\n", v.Key, v.Value)
+	}
+	return b.String()
+}
+
+// dotEnv renders the list as a .env file, quoting values where needed.
+func (l envList) dotEnv() string {
+	var b strings.Builder
+	for _, v := range l {
+		fmt.Fprintf(&b, "%s=%s\n", v.Key, quoteEnvValue(v.Value))
+	}
+	return b.String()
+}
+
+func quoteEnvValue(v string) string {
+	if v != "" && !strings.ContainsAny(v, " \t#'\"\\$`") {
+		return v
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return fmt.Sprintf("\"%s\"", r.Replace(v))
+}
+
+// isSecretEnvKey reports whether the value of key should be masked when
+// printed.
+func isSecretEnvKey(key string) bool {
+	k := strings.ToUpper(key)
+	for _, p := range secretKeyParts {
+		if strings.Contains(k, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func maskEnvValue(v string) string {
+	if v == "" {
+		return v
+	}
+	return strings.Repeat("*", 8)
+}
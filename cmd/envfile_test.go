@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDotEnv(t *testing.T) {
+	for _, tc := range []struct {
+		line string
+		want string
+	}{
+		{`FOO=bar`, "bar"},
+		{`export FOO=bar`, "bar"},
+		{`FOO=`, ""},
+		{`FOO=""`, ""},
+		{`FOO=''`, ""},
+		{`FOO=hello world`, "hello world"},
+		{`FOO="hello world"`, "hello world"},
+		{`FOO='hello world'`, "hello world"},
+		{`FOO=a=b=c`, "a=b=c"},
+		{`FOO="a=b"`, "a=b"},
+		{`FOO=bar # a comment`, "bar"},
+		{`FOO=bar#baz`, "bar#baz"},
+		{`FOO="bar # not a comment"`, "bar # not a comment"},
+		{`FOO="bar" # a comment`, "bar"},
+		{`FOO='it"s'`, `it"s`},
+		{`FOO="it's"`, "it's"},
+		{`FOO="say \"hi\""`, `say "hi"`},
+		{`FOO='no \"escapes\" here'`, `no \"escapes\" here`},
+		{`FOO="back\\slash"`, `back\slash`},
+		{`FOO="$HOME and ` + "`cmd`" + `"`, "$HOME and `cmd`"},
+		{`  FOO = bar  `, "bar"},
+	} {
+		l, err := parseDotEnv(tc.line)
+		if err != nil {
+			t.Errorf("%s: %s", tc.line, err)
+			continue
+		}
+		if got, _ := l.get("FOO"); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.line, got, tc.want)
+		}
+	}
+}
+
+func TestParseDotEnvSkipsCommentsAndRejectsBadLines(t *testing.T) {
+	l, err := parseDotEnv("# the app\n\nFOO=1\n  # indented\nexport BAR=2\nFOO=3\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (envList{{"FOO", "3"}, {"BAR", "2"}}); !reflect.DeepEqual(l, want) {
+		t.Errorf("got %v, want %v", l, want)
+	}
+
+	for _, s := range []string{"FOO", "=bar", "1FOO=bar", "FOO-BAR=1", `FOO="open`, "FOO='open", `FOO="a\nb"`} {
+		if _, err := parseDotEnv(s); err == nil {
+			t.Errorf("%s: no error", s)
+		}
+	}
+}
+
+func TestSplitEnvInput(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want envList
+	}{
+		{``, nil},
+		{`FOO=bar`, envList{{"FOO", "bar"}}},
+		{`FOO=bar  BAZ=qux`, envList{{"FOO", "bar"}, {"BAZ", "qux"}}},
+		{`GREETING="hello world" FOO=bar`, envList{{"GREETING", "hello world"}, {"FOO", "bar"}}},
+		{`GREETING='hello world'`, envList{{"GREETING", "hello world"}}},
+		{`FOO=a=b`, envList{{"FOO", "a=b"}}},
+		{`FOO=#1`, envList{{"FOO", "#1"}}},
+		{`FOO="it's" BAR='say "hi"'`, envList{{"FOO", "it's"}, {"BAR", `say "hi"`}}},
+		{`FOO= BAR=""`, envList{{"FOO", ""}, {"BAR", ""}}},
+		{"FOO=1\tBAR=2", envList{{"FOO", "1"}, {"BAR", "2"}}},
+	} {
+		got, err := splitEnvInput(tc.in)
+		if err != nil {
+			t.Errorf("%s: %s", tc.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.in, got, tc.want)
+		}
+	}
+
+	for _, s := range []string{`FOO="open`, `FOO='open`, `bar`, `1FOO=bar`} {
+		if _, err := splitEnvInput(s); err == nil {
+			t.Errorf("%s: no error", s)
+		}
+	}
+}
+
+func TestQuoteEnvValue(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want string
+	}{
+		{"bar", "bar"},
+		{"a=b", "a=b"},
+		{"", `""`},
+		{"hello world", `"hello world"`},
+		{"bar#baz", `"bar#baz"`},
+		{"it's", `"it's"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`back\slash`, `"back\\slash"`},
+		{"$HOME", `"$HOME"`},
+	} {
+		if got := quoteEnvValue(tc.in); got != tc.want {
+			t.Errorf("%q: got %s, want %s", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestUnquoteEnvValue(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want string
+	}{
+		{``, ""},
+		{`bar`, "bar"},
+		{`bar # comment`, "bar"},
+		{`""`, ""},
+		{`''`, ""},
+		{`"a\tb"`, "a\tb"},
+		{`"a\nb"`, "a\nb"},
+		{`'a\tb'`, `a\tb`},
+		{`"a" trailing`, "a"},
+	} {
+		got, err := unquoteEnvValue(tc.in)
+		if err != nil {
+			t.Errorf("%s: %s", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestDotEnvRoundTrip(t *testing.T) {
+	l := envList{
+		{"PLAIN", "bar"},
+		{"EMPTY", ""},
+		{"SPACES", "hello world"},
+		{"EQUALS", "a=b=c"},
+		{"HASH", "bar # baz"},
+		{"LEADING_HASH", "#1"},
+		{"SINGLE", "it's"},
+		{"DOUBLE", `say "hi"`},
+		{"BOTH", `it's "quoted"`},
+		{"BACKSLASH", `C:\path\`},
+		{"SHELL", "$HOME `cmd`"},
+		{"TAB", "a\tb"},
+		{"PADDED", "  padded  "},
+	}
+
+	got, err := parseDotEnv(l.dotEnv())
+	if err != nil {
+		t.Fatalf("%s\n%s", l.dotEnv(), err)
+	}
+	if !reflect.DeepEqual(got, l) {
+		t.Errorf("got %v from\n%s\nwant %v", got, l.dotEnv(), l)
+	}
+}
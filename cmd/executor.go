@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Executor is the transport used to reach the server a project is deployed
+// to. Every remote step goes through the package level executor so the
+// backend can be swapped for plain SSH or for a recording fake.
+type Executor interface {
+	// Exec runs cmd on the server wiring up the given streams.
+	Exec(cmd string, stdin io.Reader, stdout, stderr io.Writer) error
+	// Copy transfers the local file src to dst on the server.
+	Copy(src, dst string) error
+	// Download transfers the file src on the server to the local file dst.
+	Download(src, dst string) error
+	// Exists reports whether the server is known to the backend.
+	Exists() bool
+	// Status returns the state of the server, eg. "Running" or "Stopped".
+	Status() (string, error)
+	// IP returns the public address of the server.
+	IP() (string, error)
+	// Login returns the command a user can run to open a shell on the server.
+	Login() string
+}
+
+var executor Executor = dockerMachineExecutor{}
+
+var sshHost string
+var sshKey string
+
+func init() {
+	oceanCmd.PersistentFlags().StringVar(&sshHost, "ssh-host", "", "Reach the server over plain SSH (user@host) instead of docker-machine")
+	oceanCmd.PersistentFlags().StringVar(&sshKey, "ssh-key", "", "Private key to use with --ssh-host")
+}
+
+// newExecutor returns the executor for the named server based on the
+// transport flags.
+func newExecutor(name string) Executor {
+	if sshHost != "" {
+		return newSSHExecutor(sshHost, sshKey)
+	}
+	return dockerMachineExecutor{name: name}
+}
+
+func runLocal(name string, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	c := exec.Command(name, args...)
+	c.Stdin = stdin
+	c.Stdout = stdout
+	c.Stderr = stderr
+
+	if err := c.Run(); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// dockerMachineExecutor reaches the server through the docker-machine CLI.
+type dockerMachineExecutor struct {
+	name string
+}
+
+func (e dockerMachineExecutor) Exec(cmd string, stdin io.Reader, stdout, stderr io.Writer) error {
+	return runLocal("docker-machine", []string{"ssh", e.name, cmd}, stdin, stdout, stderr)
+}
+
+func (e dockerMachineExecutor) Copy(src, dst string) error {
+	d := fmt.Sprintf("%s:%s", e.name, dst)
+	return runLocal("docker-machine", []string{"scp", src, d}, os.Stdin, os.Stdout, os.Stderr)
+}
+
+func (e dockerMachineExecutor) Download(src, dst string) error {
+	s := fmt.Sprintf("%s:%s", e.name, src)
+	return runLocal("docker-machine", []string{"scp", s, dst}, os.Stdin, os.Stdout, os.Stderr)
+}
+
+func (e dockerMachineExecutor) Exists() bool {
+	out, _ := exec.Command("docker-machine", "ls", "-q").Output()
+	for _, n := range strings.Fields(string(out)) {
+		if n == e.name {
+			return true
+		}
+	}
+	return false
+}
+
+func (e dockerMachineExecutor) Status() (string, error) {
+	out, err := exec.Command("docker-machine", "status", e.name).Output()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (e dockerMachineExecutor) IP() (string, error) {
+	out, err := exec.Command("docker-machine", "ip", e.name).Output()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (e dockerMachineExecutor) Login() string {
+	ip, _ := e.IP()
+	return fmt.Sprintf("ssh root@%s -i ~/.docker/machine/machines/%s/id_rsa", ip, e.name)
+}
+
+// sshExecutor reaches the server with the system ssh and scp clients.
+type sshExecutor struct {
+	user string
+	host string
+	key  string
+}
+
+func newSSHExecutor(target, key string) sshExecutor {
+	e := sshExecutor{user: "root", host: target, key: key}
+	if i := strings.Index(target, "@"); i >= 0 {
+		e.user = target[:i]
+		e.host = target[i+1:]
+	}
+	return e
+}
+
+func (e sshExecutor) target() string {
+	return fmt.Sprintf("%s@%s", e.user, e.host)
+}
+
+func (e sshExecutor) options() []string {
+	opts := []string{"-o", "StrictHostKeyChecking=accept-new", "-o", "ConnectTimeout=10"}
+	if e.key != "" {
+		opts = append(opts, "-i", e.key)
+	}
+	return opts
+}
+
+func (e sshExecutor) Exec(cmd string, stdin io.Reader, stdout, stderr io.Writer) error {
+	args := e.options()
+	if f, ok := stdin.(*os.File); ok && isTerminal(f) {
+		args = append(args, "-t")
+	}
+	args = append(args, e.target(), cmd)
+	return runLocal("ssh", args, stdin, stdout, stderr)
+}
+
+func (e sshExecutor) Copy(src, dst string) error {
+	args := append(e.options(), src, fmt.Sprintf("%s:%s", e.target(), dst))
+	return runLocal("scp", args, os.Stdin, os.Stdout, os.Stderr)
+}
+
+func (e sshExecutor) Download(src, dst string) error {
+	args := append(e.options(), fmt.Sprintf("%s:%s", e.target(), src), dst)
+	return runLocal("scp", args, os.Stdin, os.Stdout, os.Stderr)
+}
+
+func (e sshExecutor) Exists() bool {
+	_, err := e.Status()
+	return err == nil
+}
+
+func (e sshExecutor) Status() (string, error) {
+	args := append(e.options(), "-o", "BatchMode=yes", e.target(), "true")
+	if err := exec.Command("ssh", args...).Run(); err != nil {
+		return "Error", errors.WithStack(err)
+	}
+	return "Running", nil
+}
+
+func (e sshExecutor) IP() (string, error) {
+	return e.host, nil
+}
+
+func (e sshExecutor) Login() string {
+	l := "ssh"
+	if e.key != "" {
+		l = fmt.Sprintf("%s -i %s", l, e.key)
+	}
+	return fmt.Sprintf("%s %s", l, e.target())
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// remoteOutput runs cmd on the server and returns what it wrote to stdout.
+func remoteOutput(cmd string) (string, error) {
+	var out bytes.Buffer
+	if err := executor.Exec(cmd, nil, &out, nil); err != nil {
+		return out.String(), errors.WithStack(err)
+	}
+	return out.String(), nil
+}
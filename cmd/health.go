@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// healthCheck describes the HTTP check a new web container has to pass
+// before a deploy is reported as successful.
+type healthCheck struct {
+	Path     string
+	Status   int
+	Timeout  int
+	Retries  int
+	Interval time.Duration
+}
+
+func addHealthCheckFlags(cmd *cobra.Command, p *Project) {
+	cmd.Flags().StringVar(&p.HealthPath, "health-path", "/", "Path requested to check the new container is healthy")
+	cmd.Flags().IntVar(&p.HealthStatus, "health-status", 200, "HTTP status the health check expects")
+	cmd.Flags().IntVar(&p.HealthTimeout, "health-timeout", 5, "Seconds before a single health check request times out")
+	cmd.Flags().IntVar(&p.HealthRetries, "health-retries", 30, "Number of health check attempts, two seconds apart, before giving up")
+	cmd.Flags().BoolVar(&p.AutoRollback, "auto-rollback", false, "Bring back the previous release when the health check fails")
+}
+
+// healthCheck returns the check configured for p, filling in defaults for
+// anything left unset.
+func (p Project) healthCheck() healthCheck {
+	h := healthCheck{Path: p.HealthPath, Status: p.HealthStatus, Timeout: p.HealthTimeout, Retries: p.HealthRetries, Interval: 2 * time.Second}
+	if h.Path == "" {
+		h.Path = "/"
+	}
+	if !strings.HasPrefix(h.Path, "/") {
+		h.Path = "/" + h.Path
+	}
+	if h.Status == 0 {
+		h.Status = 200
+	}
+	if h.Timeout <= 0 {
+		h.Timeout = 5
+	}
+	if h.Retries <= 0 {
+		h.Retries = 30
+	}
+	return h
+}
+
+func (h healthCheck) command(port string) string {
+	return fmt.Sprintf("curl -s -o /dev/null -m %d -w '%%{http_code}' %s", h.Timeout, shellQuote(fmt.Sprintf("http://127.0.0.1:%s%s", port, h.Path)))
+}
+
+// run polls the web container published on port until it answers with the
+// expected status or the retries run out.
+func (h healthCheck) run(port string) error {
+	color.Blue("\n==> HEALTH CHECK: GET %s on port %s expecting %d", h.Path, port, h.Status)
+
+	var last string
+	for i := 1; i <= h.Retries; i++ {
+		out, _ := remoteOutput(h.command(port))
+		last = strings.TrimSpace(out)
+		if code, err := strconv.Atoi(last); err == nil && code == h.Status {
+			color.Green("Health check passed after %d attempt(s)", i)
+			return nil
+		}
+		time.Sleep(h.Interval)
+	}
+	return errors.Errorf("health check GET %s failed after %d attempts, last status %q", h.Path, h.Retries, last)
+}
+
+// printContainerLogs shows the most recent output of a container to help
+// work out why it failed.
+func printContainerLogs(name string) {
+	color.Yellow("\n==> Recent logs of %s:", name)
+	if err := remoteCmd(fmt.Sprintf("docker container logs --tail 50 %s", name)); err != nil {
+		color.Yellow("Could not read the logs of %s: %s", name, err)
+	}
+}
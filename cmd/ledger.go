@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+)
+
+// remoteLedgerFile is the append only record of every deploy, one JSON
+// document per line.
+const remoteLedgerFile = remoteDir + "/releases.jsonl"
+
+// releaseRecord is a single entry of the release ledger.
+type releaseRecord struct {
+	Release     string    `json:"release"`
+	Ref         string    `json:"ref"`
+	SHA         string    `json:"sha"`
+	Image       string    `json:"image"`
+	Environment string    `json:"environment"`
+	User        string    `json:"user"`
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+	Outcome     string    `json:"outcome"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// deployRecord collects the details of the deploy that is running.
+var deployRecord *releaseRecord
+
+func newReleaseRecord(p Project, ref string) *releaseRecord {
+	return &releaseRecord{
+		Ref:         ref,
+		Environment: p.Environment,
+		User:        deployingUser(),
+		StartedAt:   now().UTC(),
+	}
+}
+
+// finish stamps the record with the end time and the outcome of err.
+func (r *releaseRecord) finish(err error) {
+	r.FinishedAt = now().UTC()
+	r.Outcome = "success"
+	if err != nil {
+		r.Outcome = "failed"
+		r.Error = err.Error()
+	}
+}
+
+// recordImage fills in the release, SHA and image ID of what went live.
+func (r *releaseRecord) recordImage(release string) {
+	r.Release = release
+	r.SHA = strings.SplitN(release, "-", 2)[0]
+	if out, err := remoteOutput(fmt.Sprintf("docker image inspect --format '{{.Id}}' %s", releaseImage(release))); err == nil {
+		r.Image = strings.TrimSpace(out)
+	}
+}
+
+// appendRelease adds r to the ledger on the server.
+func appendRelease(r *releaseRecord) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	cmd := fmt.Sprintf("mkdir -p %s && printf '%%s\\n' %s >> %s", remoteDir, shellQuote(string(b)), remoteLedgerFile)
+	if err := remoteCmd(cmd); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// saveRelease finishes r and appends it, only warning when the ledger can
+// not be written so the outcome of the deploy itself is kept.
+func saveRelease(r *releaseRecord, err error) {
+	r.finish(err)
+	if lerr := appendRelease(r); lerr != nil {
+		color.Yellow("\nCould not record the release in the ledger: %s", lerr)
+	}
+}
+
+// readReleases loads every record of the ledger, oldest first.
+func readReleases() ([]releaseRecord, error) {
+	out, err := remoteOutput(fmt.Sprintf("bash -c \"cat %s 2>/dev/null || true\"", remoteLedgerFile))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var rs []releaseRecord
+	for _, l := range strings.Split(out, "\n") {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		var r releaseRecord
+		if err := json.Unmarshal([]byte(l), &r); err != nil {
+			return nil, errors.Wrap(err, "could not parse the release ledger")
+		}
+		rs = append(rs, r)
+	}
+	return rs, nil
+}
+
+// deployingUser names the person running the deploy, preferring their git
+// identity.
+func deployingUser() string {
+	if out, err := exec.Command("git", "config", "user.email").Output(); err == nil {
+		if e := strings.TrimSpace(string(out)); e != "" {
+			return e
+		}
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return "unknown"
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var startCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start a stopped server and the project containers on it",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return lifecycleProject.startServer()
+	},
+}
+
+var stopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the server",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return lifecycleProject.stopServer()
+	},
+}
+
+var restartCmd = &cobra.Command{
+	Use:       "restart [web|db|proxy]",
+	Short:     "Restart a single service, or the whole server when none is given",
+	ValidArgs: logServices,
+	Args:      cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return lifecycleProject.restartServer()
+		}
+		return lifecycleProject.restartService(args[0])
+	},
+}
+
+var destroyCmd = &cobra.Command{
+	Use:   "destroy",
+	Short: "Remove the server and everything on it",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return lifecycleProject.destroyServer()
+	},
+}
+
+var lifecycleProject = Project{}
+var destroyConfirm string
+var destroyBackup bool
+
+func init() {
+	for _, c := range []*cobra.Command{startCmd, stopCmd, restartCmd, destroyCmd} {
+		c.Flags().StringVarP(&lifecycleProject.AppName, "app-name", "a", "", "The name for the application")
+		c.Flags().StringVarP(&lifecycleProject.Key, "key", "k", "", "API Key for the service the server runs on")
+		c.Flags().StringVarP(&lifecycleProject.Environment, "environment", "e", "production", "Setting for the GO_ENV variable")
+		c.Flags().BoolVar(&lifecycleProject.SkipSSL, "skip-ssl", false, "The SSL setup step was skipped for this application")
+		addDatabaseFlag(c.Flags(), &lifecycleProject)
+		addProviderFlag(c.Flags(), &lifecycleProject)
+		oceanCmd.AddCommand(c)
+	}
+	destroyCmd.Flags().StringVar(&destroyConfirm, "confirm", "", "The app name, to destroy the server without being asked")
+	destroyCmd.Flags().BoolVar(&destroyBackup, "backup", false, "Download a final database backup without being asked")
+}
+
+// startServices starts the project containers and the proxy. The
+// containers are not restarted by docker when the server boots.
+func startServices(p Project) error {
+	green := color.New(color.FgGreen).SprintFunc()
+
+	var cmds []string
+	if e, err := p.dbEngine(); err == nil && e.Image != "" {
+		cmds = append(cmds, fmt.Sprintf("docker container start %s", dbContainer))
+	}
+	cmds = append(cmds, fmt.Sprintf("docker container start %s", webContainer))
+	if !p.SkipSSL {
+		cmds = append(cmds, fmt.Sprintf("systemctl start %s", proxyService))
+	}
+
+	color.Blue("\n==> STARTING: %s", green("project services"))
+	return remoteCmd(fmt.Sprintf("bash -c \"%s\"", strings.Join(cmds, " && ")))
+}
+
+func (p Project) startServer() error {
+	p.connect()
+	pr, err := p.provider()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if s, _ := pr.Status(p); s != "Stopped" {
+		color.Yellow("\nThe server \"%s\" is already running, starting the project services.", serverName)
+	} else {
+		color.Blue("\n==> STARTING: %s", serverName)
+		if err := pr.Start(p); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return startServices(p)
+}
+
+func (p Project) stopServer() error {
+	p.connect()
+	pr, err := p.provider()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	color.Blue("\n==> STOPPING: %s", serverName)
+	return pr.Stop(p)
+}
+
+func (p Project) restartServer() error {
+	p.connect()
+	pr, err := p.provider()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	color.Blue("\n==> RESTARTING: %s", serverName)
+	if err := pr.Restart(p); err != nil {
+		return errors.WithStack(err)
+	}
+	return startServices(p)
+}
+
+func (p Project) restartService(service string) error {
+	p.connect()
+
+	var cmd string
+	switch service {
+	case "web":
+		cmd = fmt.Sprintf("docker container restart %s", webContainer)
+	case "db":
+		if e, err := p.dbEngine(); err != nil || e.Image == "" {
+			return errors.Errorf("the %s database does not run in a container", p.Database)
+		}
+		cmd = fmt.Sprintf("docker container restart %s", dbContainer)
+	case "proxy":
+		if p.SkipSSL {
+			return errors.New("there is no proxy when the SSL setup step was skipped")
+		}
+		cmd = fmt.Sprintf("systemctl restart %s", proxyService)
+	default:
+		return errors.Errorf("unknown service %q, use one of: %s", service, strings.Join(logServices, ", "))
+	}
+
+	color.Blue("\n==> RESTARTING: %s", service)
+	return remoteCmd(cmd)
+}
+
+func (p Project) destroyServer() error {
+	if p.AppName == "" {
+		return errors.New("the app name is required to destroy a server, pass --app-name")
+	}
+	p.connect()
+	pr, err := p.provider()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	color.Red("\nThis permanently removes the server \"%s\", its database and every release on it.", serverName)
+	if destroyConfirm == "" && !nonInteractive {
+		destroyConfirm = requestUserInput(fmt.Sprintf("Type the app name (%s) to confirm:", p.AppName))
+	}
+	if destroyConfirm == "" || destroyConfirm != p.AppName {
+		return errors.New("the app name did not match, nothing was destroyed")
+	}
+
+	status, _ := pr.Status(p)
+	if _, err := p.backupEngine(); err == nil && status != "Stopped" {
+		if !destroyBackup && !nonInteractive {
+			a := requestUserInput("Download a final backup of the database first? [Y/n]")
+			destroyBackup = !strings.HasPrefix(strings.ToLower(a), "n")
+		}
+		if destroyBackup {
+			if err := p.backupDatabase(); err != nil {
+				return errors.Wrap(err, "the final backup failed, nothing was destroyed")
+			}
+		}
+	}
+
+	color.Blue("\n==> DESTROYING: %s", serverName)
+	return pr.Destroy(p)
+}
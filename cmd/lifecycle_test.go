@@ -0,0 +1,26 @@
+package cmd
+
+import "testing"
+
+func TestDestroyNeedsAConfirmedAppName(t *testing.T) {
+	recordInto(t)
+	prevConfirm, prevExecutor := destroyConfirm, executor
+	defer func() { destroyConfirm, executor = prevConfirm, prevExecutor }()
+
+	for _, tc := range []struct {
+		name    string
+		appName string
+		confirm string
+	}{
+		{"no app name", "", ""},
+		{"no app name but confirmed", "", "demo"},
+		{"not confirmed", "demo", ""},
+		{"confirmed another app", "demo", "other"},
+	} {
+		destroyConfirm = tc.confirm
+		p := Project{AppName: tc.appName, Environment: "production"}
+		if err := p.destroyServer(); err == nil {
+			t.Errorf("%s: the server was destroyed", tc.name)
+		}
+	}
+}
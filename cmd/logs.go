@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// logServices are the services logs can be read from, in display order.
+var logServices = []string{"web", "db", "proxy"}
+
+// logsCmd represents the logs command
+var logsCmd = &cobra.Command{
+	Use:       "logs [web|db|proxy...]",
+	Short:     "Show the logs of the web and database containers and the proxy",
+	ValidArgs: logServices,
+	Args:      cobra.OnlyValidArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return logsProject.showLogs(args)
+	},
+}
+
+var logsProject = Project{}
+var logsFollow bool
+var logsSince string
+var logsTail int
+
+func init() {
+	logsCmd.Flags().StringVarP(&logsProject.AppName, "app-name", "a", "", "The name for the application")
+	logsCmd.Flags().StringVarP(&logsProject.Environment, "environment", "e", "production", "Setting for the GO_ENV variable")
+	logsCmd.Flags().BoolVar(&logsProject.SkipSSL, "skip-ssl", false, "The SSL setup step was skipped for this application")
+	addDatabaseFlag(logsCmd.Flags(), &logsProject)
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Keep streaming new log lines")
+	logsCmd.Flags().StringVar(&logsSince, "since", "", "Only show lines since a duration (eg. 10m) or timestamp (eg. 2019-01-02T13:04:05)")
+	logsCmd.Flags().IntVarP(&logsTail, "tail", "n", 100, "Number of lines to show from the end of the logs, 0 for all")
+	oceanCmd.AddCommand(logsCmd)
+}
+
+// logsCommand builds the command printing the logs of service.
+func logsCommand(service string) string {
+	if service == "proxy" {
+		cmd := fmt.Sprintf("journalctl -u %s --no-pager -o short-iso", proxyService)
+		if logsFollow {
+			cmd += " -f"
+		}
+		if logsSince != "" {
+			since := logsSince
+			if _, err := time.ParseDuration(since); err == nil {
+				since = "-" + since
+			}
+			cmd += fmt.Sprintf(" --since %s", shellQuote(strings.Replace(since, "T", " ", 1)))
+		}
+		if logsTail > 0 {
+			cmd += fmt.Sprintf(" -n %d", logsTail)
+		}
+		return cmd
+	}
+
+	name := webContainer
+	if service == "db" {
+		name = dbContainer
+	}
+	cmd := "docker container logs"
+	if logsFollow {
+		cmd += " -f"
+	}
+	if logsSince != "" {
+		cmd += fmt.Sprintf(" --since %s", shellQuote(logsSince))
+	}
+	if logsTail > 0 {
+		cmd += fmt.Sprintf(" --tail %d", logsTail)
+	}
+	return fmt.Sprintf("%s %s", cmd, name)
+}
+
+func (p Project) showLogs(services []string) error {
+	p.connect()
+
+	if len(services) == 0 {
+		services = logServices
+		if e, err := p.dbEngine(); err == nil && e.Image == "" {
+			services = []string{"web", "proxy"}
+		}
+		if p.SkipSSL {
+			services = services[:len(services)-1]
+		}
+	}
+
+	if len(services) == 1 {
+		return remoteCmd(logsCommand(services[0]))
+	}
+
+	colors := []color.Attribute{color.FgCyan, color.FgMagenta, color.FgYellow}
+	width := 0
+	for _, s := range services {
+		if len(s) > width {
+			width = len(s)
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errs := make([]error, len(services))
+	for i, s := range services {
+		prefix := color.New(colors[i%len(colors)]).Sprintf("%-*s | ", width, s)
+		w := &prefixWriter{prefix: prefix, out: os.Stdout, mu: &mu}
+		wg.Add(1)
+		go func(i int, s string) {
+			defer wg.Done()
+			errs[i] = executor.Exec(fmt.Sprintf("bash -c %s", shellQuote(logsCommand(s)+" 2>&1")), nil, w, w)
+			w.Flush()
+		}(i, s)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return errors.Wrapf(err, "could not read the %s logs", services[i])
+		}
+	}
+	return nil
+}
+
+// prefixWriter writes every complete line it is given to out with prefix
+// in front, sharing mu with the writers of the other services so lines
+// never interleave.
+type prefixWriter struct {
+	prefix string
+	out    io.Writer
+	mu     *sync.Mutex
+	buf    bytes.Buffer
+}
+
+func (w *prefixWriter) Write(b []byte) (int, error) {
+	w.buf.Write(b)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		line := w.buf.Next(i + 1)
+		w.mu.Lock()
+		_, err := fmt.Fprintf(w.out, "%s%s", w.prefix, line)
+		w.mu.Unlock()
+		if err != nil {
+			return len(b), errors.WithStack(err)
+		}
+	}
+}
+
+// Flush writes out a trailing line that did not end in a newline.
+func (w *prefixWriter) Flush() {
+	if w.buf.Len() == 0 {
+		return
+	}
+	w.mu.Lock()
+	fmt.Fprintf(w.out, "%s%s\n", w.prefix, w.buf.String())
+	w.mu.Unlock()
+	w.buf.Reset()
+}
@@ -20,10 +20,7 @@ func validateMachine(t string, n string) (string, bool) {
 		msg = color.RedString("\nA Docker machine with that name already exists")
 	case "isStopped":
 		rsp = validateMachineIsStopped(n)
-		msg = color.RedString("\nIt appears your Docker Machine with name \"%s\" is currently stopped.", n)
-	case "isSetup":
-		rsp = validateMachineProjectIsSetup(n)
-		msg = color.RedString("\nThe containers on the Docker Machine named \"%s\" do not appear to be setup yet or are not running. Either restart the containers before deploying or run the \"setup\" command first.", n)
+		msg = color.RedString("\nIt appears your Docker Machine with name \"%s\" is currently stopped. Start it with the \"start\" command.", n)
 	default:
 		rsp = false
 		msg = color.RedString("\nNot a valid Docker Machine check")
@@ -33,21 +30,18 @@ func validateMachine(t string, n string) (string, bool) {
 }
 
 func validateDockerMachineInstalled() bool {
+	if _, ok := executor.(dockerMachineExecutor); !ok {
+		return true
+	}
 	_, err := exec.LookPath("docker-machine")
 	return err == nil
 }
 
 func validateMachineIsStopped(n string) bool {
-	out, _ := exec.Command("docker-machine", "status", n).Output()
-	return strings.Contains(string(out), "Stopped")
+	out, _ := executor.Status()
+	return strings.Contains(out, "Stopped")
 }
 
 func validateMachineNameUnique(n string) bool {
-	out, _ := exec.Command("docker-machine", "ls").Output()
-	return strings.Contains(string(out), n)
-}
-
-func validateMachineProjectIsSetup(n string) bool {
-	out, _ := exec.Command("docker-machine", "ssh", n, "docker ps").Output()
-	return 3 == strings.Count(string(out), "\n")
+	return executor.Exists()
 }
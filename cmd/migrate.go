@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const migrateContainer = "buffaloweb_migrate"
+
+// defaultMigrateCmd is run in the new image when migrations are enabled and
+// no command was given. Buffalo's multi-stage Dockerfile only ships the app
+// binary as /bin/app, images that keep the buffalo or soda CLI use those.
+const defaultMigrateCmd = "if [ -x /bin/app ]; then /bin/app migrate; elif command -v buffalo > /dev/null; then buffalo pop migrate; else soda migrate; fi"
+
+// addMigrateFlags adds the flags enabling the migration step of a deploy.
+func addMigrateFlags(cmd *cobra.Command, p *Project) {
+	cmd.Flags().BoolVar(&p.Migrate, "migrate", false, "Run the migrations in a one-off container before the new release receives traffic")
+	cmd.Flags().StringVar(&p.MigrateCmd, "migrate-cmd", "", "Command run in the new image to migrate the database. Defaults to /bin/app migrate, falling back to buffalo pop migrate or soda migrate")
+}
+
+// runMigrations migrates the database with a throwaway container started
+// from image, before any web container uses it. It does nothing unless
+// migrations are enabled for p.
+func runMigrations(p Project, image string) error {
+	if !p.Migrate {
+		return nil
+	}
+	name := p.MigrateCmd
+	if p.MigrateCmd == "" {
+		name = "/bin/app migrate"
+		p.MigrateCmd = defaultMigrateCmd
+	}
+	if _, err := ensureDBCredentials(p, false); err != nil {
+		return errors.WithStack(err)
+	}
+
+	color.Blue("\n==> MIGRATING: %s", name)
+	if err := removeContainer(migrateContainer); err != nil {
+		return errors.WithStack(err)
+	}
+	cmd := fmt.Sprintf("docker container run --rm --name %s %s %s sh -c %s", migrateContainer, appContainerArgs(p), image, shellQuote(p.MigrateCmd))
+	if err := remoteCmd(cmd); err != nil {
+		return errors.Wrap(err, "the migrations failed, the release was not rolled out")
+	}
+	return nil
+}
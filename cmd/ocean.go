@@ -16,16 +16,70 @@ var oceanCmd = &cobra.Command{
 	},
 }
 
+// Project holds the settings of a deployed application. The yaml keys match
+// the flag names so the config file can provide defaults for any command.
 type Project struct {
-	AppName     string
-	Branch      string
-	Environment string
-	SkipVars    bool
-	SkipSSL     bool
-	Key         string
-	Tag         string
+	AppName     string   `yaml:"app-name"`
+	Branch      string   `yaml:"branch"`
+	Environment string   `yaml:"environment"`
+	SkipVars    bool     `yaml:"-"`
+	SkipSSL     bool     `yaml:"skip-ssl"`
+	Key         string   `yaml:"-"`
+	Tag         string   `yaml:"tag,omitempty"`
+	Repo        string   `yaml:"repo,omitempty"`
+	Domain      string   `yaml:"domain,omitempty"`
+	Email       string   `yaml:"email,omitempty"`
+	Provider    string   `yaml:"provider,omitempty"`
+	SSHHost     string   `yaml:"ssh-host,omitempty"`
+	SSHKey      string   `yaml:"ssh-key,omitempty"`
+	Size        string   `yaml:"size,omitempty"`
+	Region      string   `yaml:"region,omitempty"`
+	Image       string   `yaml:"image,omitempty"`
+	EnvFile     string   `yaml:"env-file,omitempty"`
+	Env         []string `yaml:"-"`
+	DeployKey   string   `yaml:"deploy-key,omitempty"`
+	Keep        int      `yaml:"keep,omitempty"`
+	Database    string   `yaml:"database,omitempty"`
+	DatabaseURL string   `yaml:"-"`
+
+	IPv6              bool `yaml:"ipv6,omitempty"`
+	PrivateNetworking bool `yaml:"private-networking,omitempty"`
+	Monitoring        bool `yaml:"monitoring,omitempty"`
+	Backups           bool `yaml:"backups,omitempty"`
+
+	DatabaseName    string            `yaml:"database-name,omitempty"`
+	DatabaseOptions map[string]string `yaml:"database-options,omitempty"`
+
+	Migrate    bool   `yaml:"migrate,omitempty"`
+	MigrateCmd string `yaml:"migrate-cmd,omitempty"`
+
+	HealthPath    string `yaml:"health-path,omitempty"`
+	HealthStatus  int    `yaml:"health-status,omitempty"`
+	HealthTimeout int    `yaml:"health-timeout,omitempty"`
+	HealthRetries int    `yaml:"health-retries,omitempty"`
+	AutoRollback  bool   `yaml:"auto-rollback,omitempty"`
 }
 
+// nonInteractive makes every command fail instead of prompting for input.
+var nonInteractive bool
+
 func init() {
+	oceanCmd.PersistentFlags().BoolVar(&nonInteractive, "non-interactive", false, "Never prompt for input, fail when a required value is missing")
 	rootCmd.AddCommand(oceanCmd)
 }
+
+// connect points the package level server name and executor at the
+// machine for the project.
+func (p Project) connect() {
+	projectName = p.AppName
+	serverName = fmt.Sprintf("%s-%s", projectName, p.Environment)
+	executor = newExecutor(serverName)
+}
+
+// webPort is the host port the buffaloweb container is published on.
+func (p Project) webPort() string {
+	if p.SkipSSL {
+		return "80"
+	}
+	return "3000"
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/pflag"
+)
+
+// dryRun makes setup and deploy print what they would do instead of doing it.
+var dryRun bool
+
+// addDryRunFlag adds the flag turning a command into a plan.
+func addDryRunFlag(fs *pflag.FlagSet) {
+	fs.BoolVar(&dryRun, "dry-run", false, "Print the steps and remote commands that would run, without running or creating anything")
+}
+
+const redacted = "[REDACTED]"
+
+var secretPatterns = []*regexp.Regexp{
+	// KEY=VALUE pairs and flags whose name gives away a secret.
+	regexp.MustCompile(`(?i)(\b[a-z_-]*(?:password|pwd|token|secret|access-key|api-key)[a-z_-]*=)[^\s'"]+`),
+	// The password of a connection URL.
+	regexp.MustCompile(`(://[^:/@\s]+:)[^@\s]+@`),
+}
+
+// planExecutor records every command instead of running it and prints it
+// with secrets redacted. Its canned answers describe a fresh server for
+// setup and a healthy one for deploy, so each step takes its usual path.
+type planExecutor struct {
+	*recordingExecutor
+	secrets []string
+}
+
+func newPlanExecutor(p Project, fresh bool) *planExecutor {
+	r := newRecordingExecutor()
+	r.MachineExists = !fresh
+	r.MachineIP = "<server ip>"
+
+	h := p.healthCheck()
+	r.Responses["curl -s -o /dev/null"] = strconv.Itoa(h.Status)
+	r.Responses["git -C buffaloproject rev-parse"] = "<sha>"
+
+	if fresh {
+		r.Responses[fmt.Sprintf("bash -c \"cat %s", remoteDBEnvFile)] = "#missing"
+	} else {
+		state := fmt.Sprintf("/%s running\n", webContainer)
+		if dbContainerCmd(p) != "" {
+			state += fmt.Sprintf("/%s running\n", dbContainer)
+		}
+		r.Responses["docker container inspect --format"] = state
+		r.Responses["docker network inspect --format"] = webNetwork
+		r.Responses["bash -c \"if [ -f /etc/caddy/Caddyfile ]"] = "active"
+		r.Responses[fmt.Sprintf("docker container port %s", webContainer)] = "0.0.0.0:" + webPorts[0]
+	}
+
+	e := &planExecutor{recordingExecutor: r}
+	for _, s := range []string{p.Key, p.DatabaseURL} {
+		if s != "" {
+			e.secrets = append(e.secrets, s)
+		}
+	}
+	return e
+}
+
+func (e *planExecutor) Exec(cmd string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fmt.Printf("    $ %s\n", e.redact(cmd))
+	return e.recordingExecutor.Exec(cmd, nil, stdout, stderr)
+}
+
+func (e *planExecutor) Copy(src, dst string) error {
+	fmt.Printf("    copy %s to %s\n", src, dst)
+	return e.recordingExecutor.Copy(src, dst)
+}
+
+func (e *planExecutor) Download(src, dst string) error {
+	fmt.Printf("    download %s to %s\n", src, dst)
+	return e.recordingExecutor.Download(src, dst)
+}
+
+// redact hides the secrets given to the plan and anything that looks like
+// a password or token in s.
+func (e *planExecutor) redact(s string) string {
+	for _, v := range e.secrets {
+		s = strings.Replace(s, v, redacted, -1)
+	}
+	s = secretPatterns[0].ReplaceAllString(s, "${1}"+redacted)
+	return secretPatterns[1].ReplaceAllString(s, "${1}"+redacted+"@")
+}
+
+// planCloudServer shows how pr would create the server. Only the commands
+// run on the server once it exists go through the executor.
+func planCloudServer(pr Provider) error {
+	e, _ := executor.(*planExecutor)
+	switch m := pr.(type) {
+	case machineProvider:
+		args := append([]string{"docker-machine", "create", serverName, fmt.Sprintf("--driver=%s", m.Driver)}, m.Flags(setup)...)
+		color.Blue("\n==> Would create docker machine: %s", serverName)
+		fmt.Printf("    $ %s\n", e.redact(strings.Join(args, " ")))
+		return nil
+	case apiProvider:
+		color.Blue("\n==> Would create droplet through the DigitalOcean API: %s (%s in %s, %s)", serverName, setup.Size, setup.Region, setup.Image)
+		return installDocker()
+	}
+	return pr.Create(&setup)
+}
+
+// fillPlanPlaceholders stands in for the values setup would ask for, so the
+// planned commands read as they will once those are given.
+func (p *Project) fillPlanPlaceholders() {
+	if p.Repo == "" {
+		p.Repo = "<repo>"
+	}
+	if !p.SkipSSL && p.Domain == "" {
+		p.Domain = "<domain>"
+	}
+	if !p.SkipSSL && p.Email == "" {
+		p.Email = "<email>"
+	}
+}
+
+// planSummary closes a dry run.
+func (e *planExecutor) planSummary() {
+	color.Yellow("\n==> DRY RUN: %d remote commands planned, nothing was run or created", len(e.Commands))
+}
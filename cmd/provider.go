@@ -0,0 +1,299 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+// Provider creates and removes the server a project is deployed to. Once
+// Create returns, the executor reaches the new server and the rest of the
+// setup runs the same for every provider.
+type Provider interface {
+	// Prepare checks the options of p, fills in the provider's defaults and
+	// shows what is about to be created, unless a resumed setup finishes a
+	// server that exists already.
+	Prepare(p *Project) error
+	// Exists reports whether the server for p was created already.
+	Exists(p Project) (bool, error)
+	// Create provisions the server for p with docker installed on it. A
+	// server left behind by an interrupted setup is finished instead.
+	Create(p *Project) error
+	// Destroy removes the server for p.
+	Destroy(p Project) error
+	// Start boots the stopped server for p.
+	Start(p Project) error
+	// Stop powers off the server for p.
+	Stop(p Project) error
+	// Restart reboots the server for p.
+	Restart(p Project) error
+	// Status returns the state of the server, eg. "Running" or "Stopped".
+	Status(p Project) (string, error)
+	// IP returns the public address of the server.
+	IP(p Project) (string, error)
+}
+
+const (
+	defaultProvider = "docker-machine"
+	sshProviderName = "ssh"
+)
+
+var providers = map[string]Provider{
+	"docker-machine": machineProvider{
+		Name:     "DigitalOcean",
+		Driver:   "digitalocean",
+		TokenURL: "https://cloud.digitalocean.com/settings/api/tokens/new",
+		Flags: func(p Project) []string {
+			return append([]string{fmt.Sprintf("--digitalocean-access-token=%s", p.Key)}, p.dropletFlags()...)
+		},
+	},
+	"digitalocean": apiProvider{},
+	"hetzner": machineProvider{
+		Name:     "Hetzner Cloud",
+		Driver:   "hetzner",
+		TokenURL: "https://console.hetzner.cloud/",
+		Size:     "cx11",
+		Region:   "nbg1",
+		Image:    "ubuntu-18.04",
+		Flags: func(p Project) []string {
+			return []string{
+				fmt.Sprintf("--hetzner-api-token=%s", p.Key),
+				fmt.Sprintf("--hetzner-server-type=%s", p.Size),
+				fmt.Sprintf("--hetzner-server-location=%s", p.Region),
+				fmt.Sprintf("--hetzner-image=%s", p.Image),
+			}
+		},
+	},
+	"linode": machineProvider{
+		Name:     "Linode",
+		Driver:   "linode",
+		TokenURL: "https://cloud.linode.com/profile/tokens",
+		Size:     "g6-nanode-1",
+		Region:   "us-east",
+		Image:    "linode/ubuntu18.04",
+		Flags: func(p Project) []string {
+			return []string{
+				fmt.Sprintf("--linode-token=%s", p.Key),
+				fmt.Sprintf("--linode-instance-type=%s", p.Size),
+				fmt.Sprintf("--linode-region=%s", p.Region),
+				fmt.Sprintf("--linode-image=%s", p.Image),
+			}
+		},
+	},
+	sshProviderName: sshHostProvider{},
+}
+
+// addProviderFlag adds the flag choosing the provider of the server.
+func addProviderFlag(fs *pflag.FlagSet, p *Project) {
+	fs.StringVar(&p.Provider, "provider", defaultProvider, fmt.Sprintf("Where the server runs (%s)", providerNames()))
+}
+
+func providerNames() string {
+	var n []string
+	for k := range providers {
+		n = append(n, k)
+	}
+	sort.Strings(n)
+	return strings.Join(n, ", ")
+}
+
+// provider returns the provider configured for p.
+func (p Project) provider() (Provider, error) {
+	n := p.Provider
+	if n == "" {
+		n = defaultProvider
+	}
+	pr, ok := providers[n]
+	if !ok {
+		return nil, errors.Errorf("%q is not a supported provider, use one of: %s", n, providerNames())
+	}
+	return pr, nil
+}
+
+// providerToken returns the API token k, asking for it when it was not
+// given.
+func providerToken(k, name, url string) string {
+	if k != "" || nonInteractive {
+		return k
+	}
+	fmt.Printf("Enter your write enabled %s API token or create one with the link below.\n", name)
+	fmt.Println(url)
+	return requestUserInput(fmt.Sprintf("Please enter your %s token:", name))
+}
+
+// machineProvider creates the server with a docker-machine driver.
+type machineProvider struct {
+	Name     string
+	Driver   string
+	TokenURL string
+	// Size, Region and Image replace the droplet defaults.
+	Size   string
+	Region string
+	Image  string
+	// Flags are the driver flags for docker-machine create.
+	Flags func(p Project) []string
+}
+
+func (m machineProvider) Prepare(p *Project) error {
+	if msg, ok := validateMachine("machineInstalled", serverName); !ok {
+		return errors.New(msg)
+	}
+
+	if m.Driver == "digitalocean" {
+		if err := p.validateDroplet(); err != nil {
+			return errors.WithStack(err)
+		}
+		if !resumingServer(m, *p) {
+			p.printDropletPlan()
+		}
+		return nil
+	}
+
+	if _, err := exec.LookPath("docker-machine-driver-" + m.Driver); err != nil {
+		return errors.Errorf("the docker-machine driver for %s is not installed, see https://docs.docker.com/machine/drivers/", m.Name)
+	}
+	if p.Size == defaultDropletSize {
+		p.Size = m.Size
+	}
+	if p.Region == defaultDropletRegion {
+		p.Region = m.Region
+	}
+	if p.Image == defaultDropletImage {
+		p.Image = m.Image
+	}
+	color.Blue("\n==> SERVER: %s %s in %s (%s)", m.Name, p.Size, p.Region, p.Image)
+	color.Yellow("    See the %s pricing for its monthly cost", m.Name)
+	return nil
+}
+
+func (m machineProvider) Exists(p Project) (bool, error) {
+	return dockerMachineExecutor{name: serverName}.Exists(), nil
+}
+
+func (m machineProvider) Create(p *Project) error {
+	green := color.New(color.FgGreen).SprintFunc()
+	if exists, _ := m.Exists(*p); exists {
+		color.Yellow("\n==> The docker machine %s exists already, finishing its setup", serverName)
+		return installDocker()
+	}
+	color.Blue("\n==> Creating docker machine: %s\n", green(serverName))
+
+	p.Key = providerToken(p.Key, m.Name, m.TokenURL)
+	args := append([]string{"create", serverName, fmt.Sprintf("--driver=%s", m.Driver)}, m.Flags(*p)...)
+	return runLocal("docker-machine", args, os.Stdin, os.Stdout, os.Stderr)
+}
+
+func (m machineProvider) Destroy(p Project) error {
+	return runLocal("docker-machine", []string{"rm", "-y", serverName}, os.Stdin, os.Stdout, os.Stderr)
+}
+
+func (m machineProvider) Start(p Project) error {
+	return runLocal("docker-machine", []string{"start", serverName}, os.Stdin, os.Stdout, os.Stderr)
+}
+
+func (m machineProvider) Stop(p Project) error {
+	return runLocal("docker-machine", []string{"stop", serverName}, os.Stdin, os.Stdout, os.Stderr)
+}
+
+func (m machineProvider) Restart(p Project) error {
+	return runLocal("docker-machine", []string{"restart", serverName}, os.Stdin, os.Stdout, os.Stderr)
+}
+
+func (m machineProvider) Status(p Project) (string, error) {
+	return dockerMachineExecutor{name: serverName}.Status()
+}
+
+func (m machineProvider) IP(p Project) (string, error) {
+	return dockerMachineExecutor{name: serverName}.IP()
+}
+
+// sshHostProvider adopts a server that already exists and is reachable
+// over SSH with --ssh-host.
+type sshHostProvider struct{}
+
+func (s sshHostProvider) Prepare(p *Project) error {
+	if sshHost == "" {
+		return errors.New("--ssh-host is required for the ssh provider")
+	}
+	// Everything is put under /root, and docker, systemctl and the Caddy
+	// config are used without sudo.
+	u := sshUser(sshHost)
+	if u == "" && !dryRun {
+		if out, err := remoteOutput("id -un"); err == nil {
+			u = strings.TrimSpace(out)
+		}
+	}
+	if u != "" && u != "root" {
+		return errors.Errorf("%s logs in as %s, an adopted server has to be reached as root, eg. root@host", sshHost, u)
+	}
+	p.SSHHost, p.SSHKey = sshHost, sshKey
+	color.Blue("\n==> SERVER: adopting %s", sshHost)
+	return nil
+}
+
+// sshUser is the user host logs in as, empty when it is left to the SSH
+// config.
+func sshUser(host string) string {
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		return host[:i]
+	}
+	return ""
+}
+
+// Exists only makes sure the host can be reached. An adopted server is
+// never created, the setup steps skip whatever is on it already.
+func (s sshHostProvider) Exists(p Project) (bool, error) {
+	if _, err := executor.Status(); err != nil {
+		return false, errors.Wrapf(err, "could not reach %s", sshHost)
+	}
+	return false, nil
+}
+
+func (s sshHostProvider) Create(p *Project) error {
+	return installDocker()
+}
+
+// Destroy removes everything setup put on the server, the server itself is
+// left alone.
+func (s sshHostProvider) Destroy(p Project) error {
+	cmds := []string{
+		fmt.Sprintf("docker container rm -f %s %s %s", webContainer, nextWebContainer, dbContainer),
+		"docker network rm buffalonet",
+		fmt.Sprintf("docker image ls -q %s | xargs -r docker image rm -f", webImage),
+		fmt.Sprintf("systemctl disable --now %s", proxyService),
+		fmt.Sprintf("rm -rf /root/buffaloproject /root/db_volume %s %s", remoteDir, backupCronFile),
+	}
+	color.Yellow("\nThe server itself is not removed, only the project is cleaned off it.")
+	return remoteCmd(fmt.Sprintf("bash -c \"%s\"", strings.Join(cmds, "; ")))
+}
+
+func (s sshHostProvider) Start(p Project) error {
+	return errors.Errorf("%s was adopted over SSH and can not be powered on from here, start it with your hosting provider", sshHost)
+}
+
+func (s sshHostProvider) Stop(p Project) error {
+	return errors.Errorf("%s was adopted over SSH and can not be powered off from here, stop it with your hosting provider", sshHost)
+}
+
+// Restart reboots the host over SSH and waits for it to come back.
+func (s sshHostProvider) Restart(p Project) error {
+	// The connection drops while the reboot is under way.
+	_ = remoteCmd("bash -c \"nohup sh -c 'sleep 1 && systemctl reboot' > /dev/null 2>&1 &\"")
+	time.Sleep(10 * time.Second)
+	return waitForSSH()
+}
+
+func (s sshHostProvider) Status(p Project) (string, error) {
+	return executor.Status()
+}
+
+func (s sshHostProvider) IP(p Project) (string, error) {
+	return executor.IP()
+}
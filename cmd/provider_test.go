@@ -0,0 +1,45 @@
+package cmd
+
+import "testing"
+
+func TestAdoptedServersAreNotPoweredOnOrOff(t *testing.T) {
+	r := recordInto(t)
+
+	pr := sshHostProvider{}
+	if err := pr.Start(Project{}); err == nil {
+		t.Error("Start succeeded for an adopted server")
+	}
+	if err := pr.Stop(Project{}); err == nil {
+		t.Error("Stop succeeded for an adopted server")
+	}
+	if len(r.Commands) != 0 {
+		t.Errorf("ran %q", r.Commands)
+	}
+}
+
+func TestAdoptedServersAreReachedAsRoot(t *testing.T) {
+	r := recordInto(t)
+	prevHost := sshHost
+	defer func() { sshHost = prevHost }()
+
+	for _, tc := range []struct {
+		host   string
+		whoami string
+		ok     bool
+	}{
+		{"root@203.0.113.10", "", true},
+		{"deploy@203.0.113.10", "", false},
+		{"203.0.113.10", "root", true},
+		{"203.0.113.10", "ubuntu", false},
+	} {
+		sshHost = tc.host
+		r.Responses["id -un"] = tc.whoami + "\n"
+		err := sshHostProvider{}.Prepare(&Project{})
+		if tc.ok && err != nil {
+			t.Errorf("%s as %q: %s", tc.host, tc.whoami, err)
+		}
+		if !tc.ok && err == nil {
+			t.Errorf("%s as %q: no error", tc.host, tc.whoami)
+		}
+	}
+}
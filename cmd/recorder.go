@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// recordingExecutor is an in-memory Executor. It never touches the network,
+// it records every command it is handed and answers with canned responses so
+// whole setup and deploy runs can be replayed offline.
+type recordingExecutor struct {
+	// Commands is the transcript of everything that was executed or copied.
+	Commands []string
+	// Responses maps a command prefix to the output returned for it.
+	Responses map[string]string
+	// Failures maps a command prefix to the error returned for it.
+	Failures map[string]error
+
+	MachineExists bool
+	MachineStatus string
+	MachineIP     string
+}
+
+func newRecordingExecutor() *recordingExecutor {
+	return &recordingExecutor{
+		Responses:     map[string]string{},
+		Failures:      map[string]error{},
+		MachineStatus: "Running",
+		MachineIP:     "127.0.0.1",
+	}
+}
+
+func (r *recordingExecutor) Exec(cmd string, stdin io.Reader, stdout, stderr io.Writer) error {
+	r.Commands = append(r.Commands, cmd)
+
+	if err := r.lookupFailure(cmd); err != nil {
+		return err
+	}
+	if out, ok := r.lookupResponse(cmd); ok && stdout != nil {
+		if _, err := io.WriteString(stdout, out); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func (r *recordingExecutor) Copy(src, dst string) error {
+	c := fmt.Sprintf("copy %s %s", src, dst)
+	r.Commands = append(r.Commands, c)
+	return r.lookupFailure(c)
+}
+
+func (r *recordingExecutor) Download(src, dst string) error {
+	c := fmt.Sprintf("download %s %s", src, dst)
+	r.Commands = append(r.Commands, c)
+	return r.lookupFailure(c)
+}
+
+func (r *recordingExecutor) Exists() bool {
+	return r.MachineExists
+}
+
+func (r *recordingExecutor) Status() (string, error) {
+	return r.MachineStatus, nil
+}
+
+func (r *recordingExecutor) IP() (string, error) {
+	return r.MachineIP, nil
+}
+
+func (r *recordingExecutor) Login() string {
+	return fmt.Sprintf("ssh root@%s", r.MachineIP)
+}
+
+// Transcript returns the recorded commands one per line, the format used
+// for golden files.
+func (r *recordingExecutor) Transcript() string {
+	if len(r.Commands) == 0 {
+		return ""
+	}
+	return strings.Join(r.Commands, "\n") + "\n"
+}
+
+func (r *recordingExecutor) lookupResponse(cmd string) (string, bool) {
+	var keys []string
+	for p := range r.Responses {
+		keys = append(keys, p)
+	}
+	p, ok := longestPrefix(cmd, keys)
+	if !ok {
+		return "", false
+	}
+	return r.Responses[p], true
+}
+
+func (r *recordingExecutor) lookupFailure(cmd string) error {
+	var keys []string
+	for p := range r.Failures {
+		keys = append(keys, p)
+	}
+	if p, ok := longestPrefix(cmd, keys); ok {
+		return r.Failures[p]
+	}
+	return nil
+}
+
+// longestPrefix picks the longest of prefixes that cmd starts with, so
+// overlapping prefixes always resolve the same way.
+func longestPrefix(cmd string, prefixes []string) (string, bool) {
+	match, ok := "", false
+	for _, p := range prefixes {
+		if strings.HasPrefix(cmd, p) && (!ok || len(p) > len(match)) {
+			match, ok = p, true
+		}
+	}
+	return match, ok
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden transcripts in testdata")
+
+// testdataDir is resolved up front, the tests chdir into a scratch checkout.
+var testdataDir, _ = filepath.Abs("testdata")
+
+// transcriptNoise matches the parts of a transcript that change from run to
+// run, with what they are replaced by.
+var transcriptNoise = []struct {
+	re   *regexp.Regexp
+	with string
+}{
+	{regexp.MustCompile(`\S*/buffalo-ocean\d+`), "<tmpfile>"},
+	{regexp.MustCompile(`"user":"[^"]*"`), `"user":"<user>"`},
+}
+
+// recordInto points executor at a fresh recordingExecutor for the duration
+// of the test and runs it from a throwaway git checkout, so local files
+// written by setup do not end up in the tree.
+func recordInto(t *testing.T) *recordingExecutor {
+	t.Helper()
+
+	r := newRecordingExecutor()
+	prevExecutor, prevNow, prevNonInteractive := executor, now, nonInteractive
+	executor = r
+	now = func() time.Time { return time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC) }
+	nonInteractive = true
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := exec.Command("git", "init", "-q", dir).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		executor, now, nonInteractive = prevExecutor, prevNow, prevNonInteractive
+		os.Chdir(wd)
+	})
+	return r
+}
+
+// assertGolden compares the transcript of r with testdata/name.golden.
+func assertGolden(t *testing.T, r *recordingExecutor, name string) {
+	t.Helper()
+
+	got := r.Transcript()
+	for _, n := range transcriptNoise {
+		got = n.re.ReplaceAllString(got, n.with)
+	}
+
+	path := filepath.Join(testdataDir, name+".golden")
+	if *update {
+		if err := ioutil.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%s, run the tests with -update to create it", err)
+	}
+	if want := string(b); got != want {
+		t.Errorf("transcript does not match %s:\n%s", path, lineDiff(want, got))
+	}
+}
+
+// lineDiff lists the lines of want and got that differ.
+func lineDiff(want, got string) string {
+	w, g := strings.Split(want, "\n"), strings.Split(got, "\n")
+	var out []string
+	for i := 0; i < len(w) || i < len(g); i++ {
+		var a, b string
+		if i < len(w) {
+			a = w[i]
+		}
+		if i < len(g) {
+			b = g[i]
+		}
+		if a != b {
+			out = append(out, "- "+a, "+ "+b)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+func TestRecordingExecutorLongestPrefix(t *testing.T) {
+	r := newRecordingExecutor()
+	r.Responses["docker"] = "short"
+	r.Responses["docker container"] = "long"
+	r.Failures["git"] = errors.New("git")
+	r.Failures["git -C buffaloproject pull"] = errors.New("pull")
+
+	for i := 0; i < 20; i++ {
+		if out, _ := remoteOutputFrom(r, "docker container ls"); out != "long" {
+			t.Fatalf("got %q for docker container ls, want long", out)
+		}
+		if out, _ := remoteOutputFrom(r, "docker image ls"); out != "short" {
+			t.Fatalf("got %q for docker image ls, want short", out)
+		}
+		if err := r.Exec("git -C buffaloproject pull", nil, nil, nil); err == nil || err.Error() != "pull" {
+			t.Fatalf("got %v for git pull, want pull", err)
+		}
+	}
+}
+
+func remoteOutputFrom(r *recordingExecutor, cmd string) (string, error) {
+	var b strings.Builder
+	err := r.Exec(cmd, nil, &b, nil)
+	return b.String(), err
+}
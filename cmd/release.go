@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+)
+
+// defaultKeepReleases is how many tagged images are kept on the server.
+const defaultKeepReleases = 5
+
+// now is the clock releases, backups and the ledger are stamped with.
+var now = time.Now
+
+// releaseImage returns the image reference for a release tag.
+func releaseImage(release string) string {
+	return fmt.Sprintf("%s:%s", webImage, release)
+}
+
+// currentSHA returns the short SHA checked out in the project on the server.
+func currentSHA() (string, error) {
+	out, err := remoteOutput("git -C buffaloproject rev-parse --short HEAD")
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// buildRelease builds the project checked out on the server and tags the
+// image with the git SHA and a timestamp, as well as latest. It returns the
+// release tag.
+func buildRelease() (string, error) {
+	sha, err := currentSHA()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	release := fmt.Sprintf("%s-%s", sha, now().UTC().Format("20060102150405"))
+
+	color.Blue("\n==> BUILDING: %s", releaseImage(release))
+	cmd := fmt.Sprintf("docker build -t %s -t %s:latest -f buffaloproject/Dockerfile buffaloproject", releaseImage(release), webImage)
+	if err := remoteCmd(cmd); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return release, nil
+}
+
+// listReleases returns the release tags present on the server, newest
+// first. Tags of a rebuild share an image ID and come back from docker in
+// any order, so they are sorted on their timestamp.
+func listReleases() ([]string, error) {
+	out, err := remoteOutput(fmt.Sprintf("docker image ls %s --format '{{.Tag}}'", webImage))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var r []string
+	for _, t := range strings.Fields(out) {
+		if t == "latest" || t == "<none>" {
+			continue
+		}
+		r = append(r, t)
+	}
+	sort.SliceStable(r, func(i, j int) bool {
+		return releaseStamp(r[i]) > releaseStamp(r[j])
+	})
+	return r, nil
+}
+
+// releaseStamp returns the timestamp suffix of a release tag, empty for
+// tags buildRelease did not create.
+func releaseStamp(release string) string {
+	i := strings.LastIndex(release, "-")
+	if i < 0 {
+		return ""
+	}
+	if _, err := time.Parse("20060102150405", release[i+1:]); err != nil {
+		return ""
+	}
+	return release[i+1:]
+}
+
+// liveRelease returns the release tag of the image the web container was
+// started from.
+func liveRelease() string {
+	out, err := remoteOutput(fmt.Sprintf("docker container inspect --format '{{.Config.Image}}' %s", webContainer))
+	if err != nil {
+		return ""
+	}
+
+	img := strings.TrimSpace(out)
+	if i := strings.LastIndex(img, ":"); i >= 0 {
+		return img[i+1:]
+	}
+	return ""
+}
+
+// pruneReleases removes all but the newest keep release images, never
+// touching the one that is live.
+func pruneReleases(keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+
+	r, err := listReleases()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(r) <= keep {
+		return nil
+	}
+
+	live := liveRelease()
+	color.Blue("\n==> Removing releases older than the last %d", keep)
+	for _, t := range r[keep:] {
+		if t == live {
+			continue
+		}
+		if err := remoteCmd(fmt.Sprintf("docker image rm %s", releaseImage(t))); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// findRelease picks the release to roll back to. An empty target means the
+// release deployed before the live one, otherwise target is matched against
+// the release tags and their SHA prefix.
+func findRelease(releases []string, live, target string) (string, error) {
+	if target == "" {
+		for i, r := range releases {
+			if r == live && i+1 < len(releases) {
+				return releases[i+1], nil
+			}
+		}
+		if live == "" && len(releases) > 1 {
+			return releases[1], nil
+		}
+		return "", errors.New("there is no earlier release to roll back to")
+	}
+
+	for _, r := range releases {
+		if r == target {
+			return r, nil
+		}
+	}
+	var m []string
+	for _, r := range releases {
+		if strings.HasPrefix(r, target) || strings.HasPrefix(target, strings.SplitN(r, "-", 2)[0]) {
+			m = append(m, r)
+		}
+	}
+	switch len(m) {
+	case 0:
+		return "", errors.Errorf("no release matching %q was found, available releases: %s", target, strings.Join(releases, ", "))
+	case 1:
+		return m[0], nil
+	}
+	return "", errors.Errorf("%q matches more than one release, use one of: %s", target, strings.Join(m, ", "))
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import "testing"
+
+func TestListReleasesSortsOnTimestamp(t *testing.T) {
+	r := recordInto(t)
+	r.Responses["docker image ls"] = "abc1234-20190101000000\nabc1234-20190103000000\nlatest\nabc1234-20190102000000\n<none>\n"
+
+	got, err := listReleases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"abc1234-20190103000000", "abc1234-20190102000000", "abc1234-20190101000000"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestFindRelease(t *testing.T) {
+	releases := []string{"abc1234-20190103000000", "abd5678-20190102000000", "ffe0000-20190101000000"}
+
+	tests := []struct {
+		target string
+		live   string
+		want   string
+		err    bool
+	}{
+		{target: "", live: "abc1234-20190103000000", want: "abd5678-20190102000000"},
+		{target: "ffe0000-20190101000000", want: "ffe0000-20190101000000"},
+		{target: "abd", want: "abd5678-20190102000000"},
+		{target: "abc1234def5678", want: "abc1234-20190103000000"},
+		{target: "ab", err: true},
+		{target: "a", err: true},
+		{target: "0000000", err: true},
+	}
+	for _, tt := range tests {
+		got, err := findRelease(releases, tt.live, tt.target)
+		if tt.err {
+			if err == nil {
+				t.Errorf("findRelease(%q) = %q, want an error", tt.target, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("findRelease(%q) = %q, %v, want %q", tt.target, got, err, tt.want)
+		}
+	}
+}
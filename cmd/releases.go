@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// releasesCmd represents the releases command
+var releasesCmd = &cobra.Command{
+	Use:   "releases",
+	Short: "Show the deploy history recorded on the server",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return releasesProject.runReleases()
+	},
+}
+
+var releasesProject = Project{}
+var releasesJSON bool
+var releasesLimit int
+
+func init() {
+	releasesCmd.Flags().StringVarP(&releasesProject.AppName, "app-name", "a", "", "The name for the application")
+	releasesCmd.Flags().StringVarP(&releasesProject.Environment, "environment", "e", "production", "Setting for the GO_ENV variable")
+	releasesCmd.Flags().BoolVar(&releasesJSON, "json", false, "Print the ledger as JSON")
+	releasesCmd.Flags().IntVarP(&releasesLimit, "limit", "n", 20, "Number of most recent releases to show, 0 for all")
+	oceanCmd.AddCommand(releasesCmd)
+}
+
+func (p Project) runReleases() error {
+	p.connect()
+
+	rs, err := readReleases()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if releasesLimit > 0 && len(rs) > releasesLimit {
+		rs = rs[len(rs)-releasesLimit:]
+	}
+
+	if releasesJSON {
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "  ")
+		if rs == nil {
+			rs = []releaseRecord{}
+		}
+		return errors.WithStack(e.Encode(rs))
+	}
+
+	if len(rs) == 0 {
+		color.Yellow("No releases have been recorded yet.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RELEASE\tREF\tSHA\tENVIRONMENT\tUSER\tSTARTED\tDURATION\tOUTCOME")
+	for i := len(rs) - 1; i >= 0; i-- {
+		r := rs[i]
+		sha := r.SHA
+		if len(sha) > 7 {
+			sha = sha[:7]
+		}
+		outcome := color.GreenString(r.Outcome)
+		if r.Outcome != "success" {
+			outcome = color.RedString(r.Outcome)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Release, r.Ref, sha, r.Environment, r.User, r.StartedAt.Local().Format("2006-01-02 15:04"), r.FinishedAt.Sub(r.StartedAt).Round(time.Second), outcome)
+	}
+	return errors.WithStack(w.Flush())
+}
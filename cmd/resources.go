@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+)
+
+const (
+	webNetwork   = "buffalonet"
+	proxyService = "caddy.service"
+)
+
+// resourceStatus is the state of one of the named pieces a project needs
+// on the server.
+type resourceStatus struct {
+	Name    string
+	Kind    string
+	State   string
+	Healthy bool
+}
+
+func (r resourceStatus) String() string {
+	return fmt.Sprintf("%s %s is %s", r.Kind, r.Name, r.State)
+}
+
+// inspectProjectResources looks up the containers, network and proxy
+// service of the project by name. The database is only checked when its
+// engine runs a container and the proxy unless SSL was skipped.
+func inspectProjectResources(p Project) []resourceStatus {
+	var rs []resourceStatus
+
+	names := []string{webContainer}
+	if dbContainerCmd(p) != "" {
+		names = append(names, dbContainer)
+	}
+
+	containers := map[string]string{}
+	out, _ := remoteOutput(fmt.Sprintf("docker container inspect --format '{{.Name}} {{.State.Status}} {{if .State.Health}}{{.State.Health.Status}}{{end}}' %s", strings.Join(names, " ")))
+	for _, l := range strings.Split(out, "\n") {
+		f := strings.Fields(l)
+		if len(f) < 2 {
+			continue
+		}
+		state := f[1]
+		if len(f) > 2 {
+			state = fmt.Sprintf("%s (%s)", f[1], f[2])
+		}
+		containers[strings.TrimPrefix(f[0], "/")] = state
+	}
+	for _, n := range names {
+		s, ok := containers[n]
+		if !ok {
+			s = "missing"
+		}
+		rs = append(rs, resourceStatus{Name: n, Kind: "container", State: s, Healthy: s == "running" || s == "running (healthy)"})
+	}
+
+	out, _ = remoteOutput(fmt.Sprintf("docker network inspect --format '{{.Name}}' %s", webNetwork))
+	n := resourceStatus{Name: webNetwork, Kind: "network", State: "missing"}
+	if strings.TrimSpace(out) == webNetwork {
+		n.State = "present"
+		n.Healthy = true
+	}
+	rs = append(rs, n)
+
+	if p.SkipSSL {
+		return rs
+	}
+	out, _ = remoteOutput(fmt.Sprintf("bash -c \"if [ -f /etc/caddy/Caddyfile ]; then systemctl is-active %s; else echo missing; fi\"", proxyService))
+	s := strings.TrimSpace(out)
+	if s == "" {
+		s = "missing"
+	}
+	return append(rs, resourceStatus{Name: proxyService, Kind: "service", State: s, Healthy: s == "active"})
+}
+
+// unhealthyResources filters rs down to the pieces that are missing or not
+// running.
+func unhealthyResources(rs []resourceStatus) []resourceStatus {
+	var u []resourceStatus
+	for _, r := range rs {
+		if !r.Healthy {
+			u = append(u, r)
+		}
+	}
+	return u
+}
+
+// repairResources brings back each of the given pieces.
+func repairResources(p Project, rs []resourceStatus) error {
+	green := color.New(color.FgGreen).SprintFunc()
+
+	if nonInteractive {
+		if m := p.missingRepairValues(rs); len(m) > 0 {
+			return errors.Errorf("missing values required for a non-interactive repair:\n  - %s", strings.Join(m, "\n  - "))
+		}
+	}
+
+	for _, r := range rs {
+		color.Blue("\n==> REPAIRING: %s", green(r.Name))
+
+		var cmd string
+		switch {
+		case r.Name == webNetwork:
+			cmd = fmt.Sprintf("docker network create --driver bridge %s", webNetwork)
+		case r.Name == proxyService && r.State == "missing":
+			if err := installCaddy(&p); err != nil {
+				return errors.Wrapf(err, "could not repair %s", r.Name)
+			}
+			if port := liveWebPort(); port != webPorts[0] {
+				if err := switchProxyUpstream(port); err != nil {
+					return errors.Wrapf(err, "could not repair %s", r.Name)
+				}
+			}
+			continue
+		case r.Name == proxyService:
+			cmd = fmt.Sprintf("systemctl restart %s", proxyService)
+		case r.Name == dbContainer && r.State == "missing":
+			c, err := ensureDBCredentials(p, false)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if err := startDBContainer(p, c); err != nil {
+				return errors.Wrapf(err, "could not repair %s", r.Name)
+			}
+			continue
+		case r.Name == webContainer && r.State == "missing":
+			// The deploy that follows creates the web container.
+			continue
+		default:
+			cmd = fmt.Sprintf("docker container restart %s", r.Name)
+		}
+
+		if err := remoteCmd(cmd); err != nil {
+			return errors.Wrapf(err, "could not repair %s", r.Name)
+		}
+	}
+	return nil
+}
+
+// missingRepairValues lists every value repairing rs would have to prompt
+// for. A missing proxy is installed again, which needs the SSL domain and
+// email.
+func (p Project) missingRepairValues(rs []resourceStatus) []string {
+	var m []string
+	for _, r := range rs {
+		if r.Name != proxyService || r.State != "missing" {
+			continue
+		}
+		if p.Domain == "" {
+			m = append(m, missingValue("domain", "SSL domain"))
+		}
+		if p.Email == "" {
+			m = append(m, missingValue("email", "SSL email"))
+		}
+	}
+	return m
+}
+
+// ensureProjectResources checks the project on the server and offers to
+// repair whatever is missing or unhealthy.
+func ensureProjectResources(p Project, repair bool) error {
+	u := unhealthyResources(inspectProjectResources(p))
+	if len(u) == 0 {
+		return nil
+	}
+
+	var lines []string
+	for _, r := range u {
+		lines = append(lines, r.String())
+	}
+	color.Red("\nThe following pieces on the Docker Machine named \"%s\" are missing or unhealthy:\n  - %s", serverName, strings.Join(lines, "\n  - "))
+
+	if !repair && !nonInteractive {
+		a := requestUserInput("Repair them now? [y/N]")
+		repair = strings.HasPrefix(strings.ToLower(a), "y")
+	}
+	if !repair {
+		return errors.New(color.RedString("\nThe containers on the Docker Machine named \"%s\" do not appear to be setup yet or are not running. Either repair them (--repair), restart the containers before deploying or run the \"setup\" command first.", serverName))
+	}
+
+	return repairResources(p, u)
+}
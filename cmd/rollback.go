@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	emoji "gopkg.in/kyokomi/emoji.v1"
+)
+
+// rollbackCmd represents the rollback command
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Recreate the web container from an earlier release without rebuilding",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return rollback.runRollback()
+	},
+}
+
+var rollback = Project{}
+var rollbackTarget string
+
+func init() {
+	rollbackCmd.Flags().StringVarP(&rollback.AppName, "app-name", "a", "", "The name for the application")
+	rollbackCmd.Flags().StringVarP(&rollback.Environment, "environment", "e", "production", "Setting for the GO_ENV variable")
+	rollbackCmd.Flags().BoolVar(&rollback.SkipSSL, "skip-ssl", false, "The SSL setup step was skipped for this application")
+	addHealthCheckFlags(rollbackCmd, &rollback)
+	addDatabaseFlag(rollbackCmd.Flags(), &rollback)
+	rollbackCmd.Flags().StringVar(&rollbackTarget, "to", "", "The git SHA or release to roll back to. Defaults to the previous release")
+	oceanCmd.AddCommand(rollbackCmd)
+}
+
+func (p Project) runRollback() error {
+	p.connect()
+	magenta := color.New(color.FgMagenta).SprintFunc()
+
+	releases, err := listReleases()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	live := liveRelease()
+
+	r, err := findRelease(releases, live, rollbackTarget)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if r == live {
+		return errors.Errorf("%s is already live", r)
+	}
+
+	color.Blue("\n==> ROLLING BACK: %s -> %s", live, r)
+	rec := newReleaseRecord(p, "rollback")
+	rec.recordImage(r)
+	if err := rollbackTo(p, r); err != nil {
+		saveRelease(rec, err)
+		return errors.WithStack(err)
+	}
+	saveRelease(rec, nil)
+
+	if _, err := emoji.Printf("\n========= :rewind: %s :rewind: =========\n", magenta("ROLLBACK COMPLETE")); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// rollbackTo makes release live again and points latest at it.
+func rollbackTo(p Project, release string) error {
+	if err := rolloutWebContainer(p, releaseImage(release)); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := remoteCmd(fmt.Sprintf("docker tag %s %s:latest", releaseImage(release), webImage)); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+)
+
+const (
+	webContainer     = "buffaloweb"
+	nextWebContainer = "buffaloweb_next"
+	webImage         = "buffaloimage"
+)
+
+// webPorts are the host ports the web container alternates between when
+// traffic is switched through the proxy.
+var webPorts = [2]string{"3000", "3001"}
+
+// appContainerArgs are the docker run arguments shared by every container
+// started from the app image: the volumes, the network and the env files.
+func appContainerArgs(p Project) string {
+	volumes := "-v /root/buffaloproject:/app"
+	if e, err := p.dbEngine(); err == nil && e.WebVolume != "" {
+		volumes = fmt.Sprintf("%s -v %s", volumes, e.WebVolume)
+	}
+	return fmt.Sprintf("%s --network=buffalonet --env-file %s --env-file %s -e GO_ENV=%s", volumes, remoteDBEnvFile, remoteEnvFile, p.Environment)
+}
+
+// webContainerCmd builds the docker command that starts a web container
+// from image with the persisted env file applied.
+func webContainerCmd(p Project, name, port, image string) string {
+	return fmt.Sprintf("docker container run -it --name %s -p %s:3000 %s -d %s", name, port, appContainerArgs(p), image)
+}
+
+// rolloutWebContainer replaces the running web container with one started
+// from image. When traffic goes through the proxy the new container is
+// started next to the old one and only receives traffic once it answers,
+// otherwise the old container is replaced in place.
+func rolloutWebContainer(p Project, image string) error {
+	if _, err := ensureDBCredentials(p, false); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if p.SkipSSL {
+		return replaceWebContainer(p, image)
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	live := liveWebPort()
+	next := webPorts[0]
+	if live == webPorts[0] {
+		next = webPorts[1]
+	}
+
+	color.Blue("\n==> STARTING: %s on port %s", green(nextWebContainer), next)
+	if err := removeContainer(nextWebContainer); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := remoteCmd(webContainerCmd(p, nextWebContainer, next, image)); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := p.healthCheck().run(next); err != nil {
+		printContainerLogs(nextWebContainer)
+		if rerr := removeContainer(nextWebContainer); rerr != nil {
+			return errors.WithStack(rerr)
+		}
+		return errors.Wrapf(err, "the new container never became healthy, %s was left serving", webContainer)
+	}
+
+	if err := switchProxyUpstream(next); err != nil {
+		return errors.WithStack(err)
+	}
+
+	color.Blue("\n==> RETIRING: %s", green(webContainer))
+	if err := removeContainer(webContainer); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := remoteCmd(fmt.Sprintf("docker container rename %s %s", nextWebContainer, webContainer)); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// replaceWebContainer stops the running web container and starts a new one
+// on the same port. If the new one fails its health check the previous
+// image is brought back when auto rollback is enabled.
+func replaceWebContainer(p Project, image string) error {
+	green := color.New(color.FgGreen).SprintFunc()
+	color.Blue("\n==> REPLACING: %s", green(webContainer))
+
+	previous := liveRelease()
+	if err := removeContainer(webContainer); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := remoteCmd(webContainerCmd(p, webContainer, p.webPort(), image)); err != nil {
+		return errors.WithStack(err)
+	}
+
+	err := p.healthCheck().run(p.webPort())
+	if err == nil {
+		return nil
+	}
+	printContainerLogs(webContainer)
+
+	if !p.AutoRollback || previous == "" || releaseImage(previous) == image {
+		return errors.WithStack(err)
+	}
+	color.Yellow("\n==> Rolling back to %s", previous)
+	if rerr := removeContainer(webContainer); rerr != nil {
+		return errors.WithStack(rerr)
+	}
+	if rerr := remoteCmd(webContainerCmd(p, webContainer, p.webPort(), releaseImage(previous))); rerr != nil {
+		return errors.WithStack(rerr)
+	}
+	return errors.Wrapf(err, "rolled back to %s", previous)
+}
+
+// restartWebContainer recreates buffaloweb from the live release so changes
+// to the env file are picked up.
+func restartWebContainer(p Project) error {
+	image := webImage
+	if r := liveRelease(); r != "" && r != "latest" {
+		image = releaseImage(r)
+	}
+	return rolloutWebContainer(p, image)
+}
+
+func removeContainer(name string) error {
+	return remoteCmd(fmt.Sprintf("bash -c \"docker container rm -f %s > /dev/null 2>&1 || true\"", name))
+}
+
+// liveWebPort returns the host port the running web container is published
+// on, falling back to the port used by setup.
+func liveWebPort() string {
+	out, err := remoteOutput(fmt.Sprintf("docker container port %s 3000", webContainer))
+	if err != nil {
+		return webPorts[0]
+	}
+
+	for _, l := range strings.Split(strings.TrimSpace(out), "\n") {
+		if i := strings.LastIndex(l, ":"); i >= 0 {
+			return strings.TrimSpace(l[i+1:])
+		}
+	}
+	return webPorts[0]
+}
+
+// switchProxyUpstream points Caddy at the web container on port.
+func switchProxyUpstream(port string) error {
+	color.Blue("\n==> Switching proxy upstream to port %s", port)
+	cmd := fmt.Sprintf("bash -c \"sed -i -E 's#http://127.0.0.1:[0-9]+#http://127.0.0.1:%s#' /etc/caddy/Caddyfile && systemctl reload caddy.service\"", port)
+
+	if err := remoteCmd(cmd); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
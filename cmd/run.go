@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run -- COMMAND [ARGS...]",
+	Short: "Run a command in a throwaway container of the deployed image",
+	Example: `  buffalo ocean run -a myapp -- buffalo pop migrate status
+  buffalo ocean run -a myapp -- sh`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runProject.runOneOff(args)
+	},
+}
+
+// taskCmd represents the task command
+var taskCmd = &cobra.Command{
+	Use:   "task NAME [ARGS...]",
+	Short: "Run a buffalo task in a throwaway container of the deployed image",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runProject.runOneOff(taskArgs(args))
+	},
+}
+
+// taskScript runs the task given as "$@" with the app binary, which is all
+// Buffalo's multi-stage Dockerfile ships, and with the buffalo CLI in images
+// without /bin/app.
+const taskScript = `if [ -x /bin/app ]; then exec /bin/app task "$@"; fi; exec buffalo task "$@"`
+
+// taskArgs is the one-off command running the task args.
+func taskArgs(args []string) []string {
+	return append([]string{"sh", "-c", taskScript, "task"}, args...)
+}
+
+var runProject = Project{}
+
+func init() {
+	for _, c := range []*cobra.Command{runCmd, taskCmd} {
+		c.Flags().StringVarP(&runProject.AppName, "app-name", "a", "", "The name for the application")
+		c.Flags().StringVarP(&runProject.Environment, "environment", "e", "production", "Setting for the GO_ENV variable")
+		addDatabaseFlag(c.Flags(), &runProject)
+		oceanCmd.AddCommand(c)
+	}
+}
+
+// currentImage is the image of the live release, or the latest build when
+// the web container is not running.
+func currentImage() string {
+	if r := liveRelease(); r != "" {
+		return releaseImage(r)
+	}
+	return fmt.Sprintf("%s:latest", webImage)
+}
+
+// runOneOff runs args in a container that is removed once it exits. It
+// gets the same network and env as the web container.
+func (p Project) runOneOff(args []string) error {
+	p.connect()
+
+	if err := remoteCmd(oneOffCommand(p, currentImage(), args)); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// oneOffCommand builds the command running args in image. A TTY is only
+// asked for when the remote shell has one, docker refuses -t otherwise,
+// which is the case with piped input or in CI.
+func oneOffCommand(p Project, image string, args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = shellQuote(a)
+	}
+
+	run := fmt.Sprintf("docker container run --rm $tty %s %s %s", appContainerArgs(p), image, strings.Join(quoted, " "))
+	return fmt.Sprintf("bash -c %s", shellQuote("tty=-i; if [ -t 0 ]; then tty=-it; fi; exec "+run))
+}
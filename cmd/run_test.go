@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOneOffCommandWithoutATTY(t *testing.T) {
+	p := Project{AppName: "demo", Environment: "production", Database: "postgres"}
+	cmd := oneOffCommand(p, releaseImage("abc1234-20190102030405"), []string{"echo", "it's $HOME"})
+
+	// Run it locally with docker swapped for echo and stdin not a terminal.
+	local := strings.Replace(cmd, "exec docker container run", "exec echo", 1)
+	c := exec.Command("sh", "-c", local)
+	c.Stdin = strings.NewReader("")
+	out, err := c.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(out), "--rm -i ") {
+		t.Errorf("got %q, want -i without a TTY", out)
+	}
+	if !strings.HasSuffix(string(out), "buffaloimage:abc1234-20190102030405 echo it's $HOME\n") {
+		t.Errorf("got %q, the arguments were not passed on as given", out)
+	}
+}
+
+func TestTaskFallsBackToTheBuffaloCLI(t *testing.T) {
+	if _, err := os.Stat("/bin/app"); err == nil {
+		t.Skip("/bin/app exists on this machine")
+	}
+
+	// A stand-in buffalo CLI that prints the arguments it was given.
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "buffalo"), []byte("#!/bin/sh\nfor a in \"$@\"; do echo \"[$a]\"; done\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	args := taskArgs([]string{"db:seed", "two words", "it's"})
+	c := exec.Command(args[0], args[1:]...)
+	c.Env = append(os.Environ(), "PATH="+dir+":"+os.Getenv("PATH"))
+	out, err := c.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[task]\n[db:seed]\n[two words]\n[it's]\n"; string(out) != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestProvisionProcess(t *testing.T) {
+	r := recordInto(t)
+	r.Responses[fmt.Sprintf("bash -c \"cat %s", remoteDBEnvFile)] = "#missing"
+	r.Responses["git -C buffaloproject rev-parse --short HEAD"] = "abc1234"
+
+	prevSetup, prevHost := setup, sshHost
+	defer func() { setup, sshHost = prevSetup, prevHost }()
+	sshHost = "root@203.0.113.10"
+	setup = Project{
+		AppName:     "demo",
+		Branch:      "master",
+		Environment: "production",
+		Provider:    sshProviderName,
+		Repo:        "git@github.com:demo/demo.git",
+		Domain:      "demo.example.com",
+		Email:       "ops@example.com",
+		Env:         []string{"FOO=bar"},
+		Database:    "postgres",
+	}
+	projectName, serverName = "demo", "demo-production"
+
+	if err := provisionProcess(setup); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, r, "setup")
+}
+
+func TestProvisionProcessResume(t *testing.T) {
+	r := recordInto(t)
+	r.Responses[fmt.Sprintf("bash -c \"cat %s", remoteSetupFile)] = "create-server\nswap\ndeploy-key\nclone\nenv-file\nenv-vars\nnetwork\n"
+	r.Responses[fmt.Sprintf("bash -c \"cat %s", remoteDBEnvFile)] = "POSTGRES_USER=buffalo\nPOSTGRES_PASSWORD=secret\nPOSTGRES_DB=demo_production\n"
+	r.Responses["git -C buffaloproject rev-parse --short HEAD"] = "abc1234"
+
+	prevSetup, prevHost, prevResume := setup, sshHost, setupResume
+	defer func() { setup, sshHost, setupResume = prevSetup, prevHost, prevResume }()
+	sshHost = "root@203.0.113.10"
+	setupResume = true
+	setup = Project{
+		AppName:     "demo",
+		Branch:      "master",
+		Environment: "production",
+		Provider:    sshProviderName,
+		Repo:        "git@github.com:demo/demo.git",
+		Env:         []string{"FOO=bar"},
+		SkipSSL:     true,
+		Database:    "postgres",
+	}
+	projectName, serverName = "demo", "demo-production"
+
+	if err := provisionProcess(setup); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, r, "setup_resume")
+}
+
+func TestProvisionProcessResumeFinishesTheServer(t *testing.T) {
+	r := recordInto(t)
+	r.Responses[fmt.Sprintf("bash -c \"cat %s", remoteSetupFile)] = ""
+	r.Responses["git -C buffaloproject rev-parse --short HEAD"] = "abc1234"
+
+	prevSetup, prevHost, prevResume := setup, sshHost, setupResume
+	defer func() { setup, sshHost, setupResume = prevSetup, prevHost, prevResume }()
+	sshHost = "root@203.0.113.10"
+	setupResume = true
+	setup = Project{
+		AppName:     "demo",
+		Branch:      "master",
+		Environment: "production",
+		Provider:    sshProviderName,
+		Repo:        "git@github.com:demo/demo.git",
+		SkipVars:    true,
+		SkipSSL:     true,
+		Database:    "none",
+	}
+	projectName, serverName = "demo", "demo-production"
+
+	if err := provisionProcess(setup); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Commands) < 3 || !strings.Contains(r.Commands[1], "command -v docker") || !strings.HasSuffix(r.Commands[2], "echo create-server >> "+remoteSetupFile+"\"") {
+		t.Errorf("create-server was not run again, ran %q", r.Commands)
+	}
+}
+
+func TestSetupDatabaseKeepsTheLegacyCredentials(t *testing.T) {
+	r := recordInto(t)
+	r.Responses[fmt.Sprintf("bash -c \"cat %s", remoteDBEnvFile)] = "#missing"
+	r.Responses[fmt.Sprintf("bash -c \"if docker container inspect %s", dbContainer)] = "yes"
+
+	prevSetup := setup
+	defer func() { setup = prevSetup }()
+	setup = Project{AppName: "demo", Environment: "production", Database: "postgres"}
+	files := copyingExecutor{r, map[string]string{}}
+	executor = files
+
+	if err := setupDatabase(); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, r, "setup_database_legacy")
+	if got := files.copied[remoteDBEnvFile]; !strings.Contains(got, "POSTGRES_USER=admin") || !strings.Contains(got, "POSTGRES_PASSWORD=password") {
+		t.Errorf("stored %q, want the legacy credentials", got)
+	}
+}
+
+// copyingExecutor keeps what was copied to the server by destination.
+type copyingExecutor struct {
+	*recordingExecutor
+	copied map[string]string
+}
+
+func (c copyingExecutor) Copy(src, dst string) error {
+	b, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	c.copied[dst] = string(b)
+	return c.recordingExecutor.Copy(src, dst)
+}
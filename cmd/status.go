@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the state of the server, its containers, the deployed release and the TLS certificate",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return statusProject.runStatus()
+	},
+}
+
+var statusProject = Project{}
+var statusJSON bool
+
+func init() {
+	statusCmd.Flags().StringVarP(&statusProject.AppName, "app-name", "a", "", "The name for the application")
+	statusCmd.Flags().StringVarP(&statusProject.Environment, "environment", "e", "production", "Setting for the GO_ENV variable")
+	statusCmd.Flags().BoolVar(&statusProject.SkipSSL, "skip-ssl", false, "The SSL setup step was skipped for this application")
+	statusCmd.Flags().StringVar(&statusProject.Domain, "domain", "", "The site domain the TLS certificate is checked for, read from the server when not given")
+	statusCmd.Flags().StringVarP(&statusProject.Key, "key", "k", "", "API Key for the service the server runs on")
+	addProviderFlag(statusCmd.Flags(), &statusProject)
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "Print the status as JSON")
+	oceanCmd.AddCommand(statusCmd)
+}
+
+type containerInfo struct {
+	Name   string `json:"name"`
+	State  string `json:"state"`
+	Status string `json:"status"`
+	Image  string `json:"image"`
+}
+
+type usage struct {
+	Total uint64 `json:"total"`
+	Used  uint64 `json:"used"`
+}
+
+func (u usage) String() string {
+	if u.Total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%s / %s (%d%%)", humanSize(int64(u.Used)), humanSize(int64(u.Total)), u.Used*100/u.Total)
+}
+
+type certInfo struct {
+	Domain  string     `json:"domain"`
+	Valid   bool       `json:"valid"`
+	Expires *time.Time `json:"expires,omitempty"`
+	Error   string     `json:"error,omitempty"`
+}
+
+// serverStatus is everything the status command reports.
+type serverStatus struct {
+	Server     string          `json:"server"`
+	State      string          `json:"state"`
+	IP         string          `json:"ip,omitempty"`
+	Containers []containerInfo `json:"containers"`
+	Release    string          `json:"release,omitempty"`
+	SHA        string          `json:"sha,omitempty"`
+	Ref        string          `json:"ref,omitempty"`
+	Disk       usage           `json:"disk"`
+	Memory     usage           `json:"memory"`
+	Swap       usage           `json:"swap"`
+	TLS        *certInfo       `json:"tls,omitempty"`
+}
+
+func (p Project) runStatus() error {
+	p.connect()
+
+	pr, err := p.provider()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	s := serverStatus{Server: serverName, Containers: []containerInfo{}}
+	if s.State, err = pr.Status(p); err != nil && s.State == "" {
+		s.State = "Unknown"
+	}
+	if s.State == "Running" {
+		s.collect(p, pr)
+	}
+
+	if statusJSON {
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "  ")
+		return errors.WithStack(e.Encode(s))
+	}
+	return s.print()
+}
+
+// collect fills in the details that can only be read from a running server.
+func (s *serverStatus) collect(p Project, pr Provider) {
+	s.IP, _ = pr.IP(p)
+
+	out, _ := remoteOutput("docker container ls -a --filter name=^buffalo --format '{{.Names}}\t{{.State}}\t{{.Status}}\t{{.Image}}'")
+	for _, l := range strings.Split(strings.TrimSpace(out), "\n") {
+		if f := strings.Split(l, "\t"); len(f) == 4 {
+			s.Containers = append(s.Containers, containerInfo{Name: f[0], State: f[1], Status: f[2], Image: f[3]})
+		}
+	}
+
+	s.Release = liveRelease()
+	if out, err := remoteOutput("git -C buffaloproject rev-parse HEAD"); err == nil {
+		s.SHA = strings.TrimSpace(out)
+	}
+	if out, err := remoteOutput("bash -c \"git -C buffaloproject describe --tags --exact-match 2>/dev/null || git -C buffaloproject rev-parse --abbrev-ref HEAD\""); err == nil {
+		s.Ref = strings.TrimSpace(out)
+	}
+
+	if out, err := remoteOutput("df -B1 --output=size,used /"); err == nil {
+		if f := strings.Fields(out); len(f) >= 4 {
+			s.Disk = parseUsage(f[2], f[3])
+		}
+	}
+	if out, err := remoteOutput("free -b"); err == nil {
+		for _, l := range strings.Split(out, "\n") {
+			f := strings.Fields(l)
+			if len(f) < 3 {
+				continue
+			}
+			switch f[0] {
+			case "Mem:":
+				s.Memory = parseUsage(f[1], f[2])
+			case "Swap:":
+				s.Swap = parseUsage(f[1], f[2])
+			}
+		}
+	}
+
+	if p.SkipSSL {
+		return
+	}
+	if p.Domain == "" {
+		out, _ := remoteOutput("bash -c \"head -n 1 /etc/caddy/Caddyfile 2>/dev/null | awk '{print \\$1}'\"")
+		p.Domain = strings.TrimSpace(out)
+	}
+	if p.Domain != "" {
+		s.TLS = checkCertificate(p.Domain)
+	}
+}
+
+func parseUsage(total, used string) usage {
+	t, _ := strconv.ParseUint(total, 10, 64)
+	u, _ := strconv.ParseUint(used, 10, 64)
+	return usage{Total: t, Used: u}
+}
+
+// checkCertificate connects to domain and reports whether its certificate
+// verifies and when it expires.
+func checkCertificate(domain string) *certInfo {
+	c := &certInfo{Domain: domain}
+	d := &net.Dialer{Timeout: 10 * time.Second}
+	host := net.JoinHostPort(domain, "443")
+
+	conn, err := tls.DialWithDialer(d, "tcp", host, &tls.Config{ServerName: domain})
+	if err != nil {
+		c.Error = err.Error()
+		// Connect again without verifying to still learn the expiry date.
+		if conn, err = tls.DialWithDialer(d, "tcp", host, &tls.Config{ServerName: domain, InsecureSkipVerify: true}); err != nil {
+			return c
+		}
+	} else {
+		c.Valid = true
+	}
+	defer conn.Close()
+
+	if certs := conn.ConnectionState().PeerCertificates; len(certs) > 0 {
+		c.Expires = &certs[0].NotAfter
+	}
+	return c
+}
+
+func (s serverStatus) print() error {
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+
+	state := green(s.State)
+	if s.State != "Running" {
+		state = red(s.State)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Server:\t%s (%s)\n", s.Server, state)
+	if s.State != "Running" {
+		return errors.WithStack(w.Flush())
+	}
+	fmt.Fprintf(w, "IP:\t%s\n", s.IP)
+	fmt.Fprintf(w, "Release:\t%s\n", orDash(s.Release))
+	fmt.Fprintf(w, "Checkout:\t%s %s\n", orDash(s.Ref), shortSHA(s.SHA))
+	fmt.Fprintf(w, "Disk:\t%s\n", s.Disk)
+	fmt.Fprintf(w, "Memory:\t%s\n", s.Memory)
+	fmt.Fprintf(w, "Swap:\t%s\n", s.Swap)
+	if s.TLS != nil {
+		cert := green("valid")
+		if !s.TLS.Valid {
+			cert = red("invalid: " + s.TLS.Error)
+		}
+		if s.TLS.Expires != nil {
+			days := int(time.Until(*s.TLS.Expires).Hours() / 24)
+			cert = fmt.Sprintf("%s, expires %s (%d days)", cert, s.TLS.Expires.Local().Format("2006-01-02"), days)
+		}
+		fmt.Fprintf(w, "TLS (%s):\t%s\n", s.TLS.Domain, cert)
+	}
+	if err := w.Flush(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTAINER\tSTATE\tSTATUS\tIMAGE")
+	for _, c := range s.Containers {
+		st := green(c.State)
+		if c.State != "running" {
+			st = red(c.State)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Name, st, c.Status, c.Image)
+	}
+	return errors.WithStack(w.Flush())
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
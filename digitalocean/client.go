@@ -0,0 +1,265 @@
+// Package digitalocean manages the droplets projects are deployed to
+// through the DigitalOcean API.
+package digitalocean
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/pkg/errors"
+)
+
+// Tag is put on every droplet created by the plugin.
+const Tag = "buffalo-ocean"
+
+// Client talks to the DigitalOcean API.
+type Client struct {
+	api *godo.Client
+	// PollInterval is how long to wait between checks while a droplet is
+	// being created.
+	PollInterval time.Duration
+}
+
+// NewClient returns a client authenticating with token. An empty baseURL
+// uses the public API, anything else points the client at a stand-in.
+func NewClient(token, baseURL string) (*Client, error) {
+	hc := &http.Client{Transport: tokenTransport{token: token}, Timeout: time.Minute}
+
+	opts := []godo.ClientOpt{godo.SetUserAgent("buffalo-ocean")}
+	if baseURL != "" {
+		opts = append(opts, godo.SetBaseURL(baseURL))
+	}
+	api, err := godo.New(hc, opts...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &Client{api: api, PollInterval: 5 * time.Second}, nil
+}
+
+type tokenTransport struct {
+	token string
+}
+
+func (t tokenTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.Header = make(http.Header, len(r.Header))
+	for k, v := range r.Header {
+		r2.Header[k] = v
+	}
+	r2.Header.Set("Authorization", "Bearer "+t.token)
+	return http.DefaultTransport.RoundTrip(r2)
+}
+
+// DropletRequest describes the droplet to create.
+type DropletRequest struct {
+	Name              string
+	Region            string
+	Size              string
+	Image             string
+	IPv6              bool
+	PrivateNetworking bool
+	Monitoring        bool
+	Backups           bool
+	// SSHKeyFingerprint is the uploaded key root can log in with.
+	SSHKeyFingerprint string
+	// Tags are added to Tag. The droplet is only found again by FindDroplet
+	// when its name is one of them.
+	Tags []string
+}
+
+// Droplet is the part of a droplet the plugin cares about.
+type Droplet struct {
+	ID     int
+	Name   string
+	Status string
+	IPv4   string
+	IPv6   string
+	Tags   []string
+}
+
+func newDroplet(d *godo.Droplet) Droplet {
+	ipv4, _ := d.PublicIPv4()
+	ipv6, _ := d.PublicIPv6()
+	return Droplet{ID: d.ID, Name: d.Name, Status: d.Status, IPv4: ipv4, IPv6: ipv6, Tags: d.Tags}
+}
+
+// Fingerprint returns the MD5 fingerprint DigitalOcean identifies the
+// authorized_keys formatted publicKey by.
+func Fingerprint(publicKey string) (string, error) {
+	f := strings.Fields(publicKey)
+	if len(f) < 2 {
+		return "", errors.New("the public key is not in the authorized_keys format")
+	}
+	b, err := base64.StdEncoding.DecodeString(f[1])
+	if err != nil {
+		return "", errors.Wrap(err, "the public key is not in the authorized_keys format")
+	}
+
+	sum := md5.Sum(b)
+	hex := make([]string, len(sum))
+	for i, c := range sum {
+		hex[i] = fmt.Sprintf("%02x", c)
+	}
+	return strings.Join(hex, ":"), nil
+}
+
+// UploadSSHKey adds publicKey to the account unless it is there already and
+// returns its fingerprint.
+func (c *Client) UploadSSHKey(ctx context.Context, name, publicKey string) (string, error) {
+	fp, err := Fingerprint(publicKey)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	if _, resp, err := c.api.Keys.GetByFingerprint(ctx, fp); err == nil {
+		return fp, nil
+	} else if resp == nil || resp.StatusCode != http.StatusNotFound {
+		return "", errors.Wrap(err, "could not look up the SSH key")
+	}
+
+	k, _, err := c.api.Keys.Create(ctx, &godo.KeyCreateRequest{Name: name, PublicKey: strings.TrimSpace(publicKey)})
+	if err != nil {
+		return "", errors.Wrap(err, "could not upload the SSH key")
+	}
+	return k.Fingerprint, nil
+}
+
+// CreateDroplet creates the droplet described by r and tags it with Tag.
+// The droplet is not ready yet, see WaitForDroplet.
+func (c *Client) CreateDroplet(ctx context.Context, r DropletRequest) (Droplet, error) {
+	req := &godo.DropletCreateRequest{
+		Name:              r.Name,
+		Region:            r.Region,
+		Size:              r.Size,
+		Image:             godo.DropletCreateImage{Slug: r.Image},
+		IPv6:              r.IPv6,
+		PrivateNetworking: r.PrivateNetworking,
+		Monitoring:        r.Monitoring,
+		Backups:           r.Backups,
+		Tags:              append([]string{Tag}, r.Tags...),
+	}
+	if r.SSHKeyFingerprint != "" {
+		req.SSHKeys = []godo.DropletCreateSSHKey{{Fingerprint: r.SSHKeyFingerprint}}
+	}
+
+	d, _, err := c.api.Droplets.Create(ctx, req)
+	if err != nil {
+		return Droplet{}, errors.Wrapf(err, "could not create the droplet %s", r.Name)
+	}
+	return newDroplet(d), nil
+}
+
+// WaitForDroplet polls the droplet until it is active and has a public
+// address, or ctx is done.
+func (c *Client) WaitForDroplet(ctx context.Context, id int) (Droplet, error) {
+	t := time.NewTicker(c.PollInterval)
+	defer t.Stop()
+
+	for {
+		d, _, err := c.api.Droplets.Get(ctx, id)
+		if err != nil {
+			return Droplet{}, errors.Wrapf(err, "could not get the droplet %d", id)
+		}
+		if dd := newDroplet(d); dd.Status == "active" && dd.IPv4 != "" {
+			return dd, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return Droplet{}, errors.Wrapf(ctx.Err(), "the droplet %d did not become active", id)
+		case <-t.C:
+		}
+	}
+}
+
+// FindDroplet returns the droplet with the given name among the droplets
+// tagged with it, see DropletRequest.Tags. The bool is false when there is
+// none.
+func (c *Client) FindDroplet(ctx context.Context, name string) (Droplet, bool, error) {
+	ds, resp, err := c.api.Droplets.ListByTag(ctx, name, &godo.ListOptions{PerPage: 200})
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return Droplet{}, false, nil
+		}
+		return Droplet{}, false, errors.Wrapf(err, "could not list the droplets tagged %s", name)
+	}
+
+	for _, d := range ds {
+		if d.Name == name {
+			return newDroplet(&d), true, nil
+		}
+	}
+	return Droplet{}, false, nil
+}
+
+// DeleteSSHKey removes the key with the given fingerprint from the
+// account. A key that is already gone is not an error.
+func (c *Client) DeleteSSHKey(ctx context.Context, fingerprint string) error {
+	if resp, err := c.api.Keys.DeleteByFingerprint(ctx, fingerprint); err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		return errors.Wrapf(err, "could not delete the SSH key %s", fingerprint)
+	}
+	return nil
+}
+
+// DeleteDroplet removes the droplet with the given id.
+func (c *Client) DeleteDroplet(ctx context.Context, id int) error {
+	if _, err := c.api.Droplets.Delete(ctx, id); err != nil {
+		return errors.Wrapf(err, "could not delete the droplet %d", id)
+	}
+	return nil
+}
+
+// PowerOn boots the droplet with the given id and waits until it is on.
+func (c *Client) PowerOn(ctx context.Context, id int) error {
+	return c.runAction(ctx, id, "power on", c.api.DropletActions.PowerOn)
+}
+
+// Shutdown gracefully powers off the droplet with the given id and waits
+// until it is off.
+func (c *Client) Shutdown(ctx context.Context, id int) error {
+	return c.runAction(ctx, id, "shut down", c.api.DropletActions.Shutdown)
+}
+
+// Reboot gracefully restarts the droplet with the given id and waits until
+// it is back on.
+func (c *Client) Reboot(ctx context.Context, id int) error {
+	return c.runAction(ctx, id, "reboot", c.api.DropletActions.Reboot)
+}
+
+// runAction starts a droplet action and polls it until it completes.
+func (c *Client) runAction(ctx context.Context, id int, what string, start func(context.Context, int) (*godo.Action, *godo.Response, error)) error {
+	a, _, err := start(ctx, id)
+	if err != nil {
+		return errors.Wrapf(err, "could not %s the droplet %d", what, id)
+	}
+
+	t := time.NewTicker(c.PollInterval)
+	defer t.Stop()
+	for {
+		switch a.Status {
+		case godo.ActionCompleted:
+			return nil
+		case "errored":
+			return errors.Errorf("could not %s the droplet %d", what, id)
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "the droplet %d did not %s", id, what)
+		case <-t.C:
+		}
+		if a, _, err = c.api.DropletActions.Get(ctx, id, a.ID); err != nil {
+			return errors.Wrapf(err, "could not check the %s of the droplet %d", what, id)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package digitalocean
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testKey = "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAAAgQC7 demo-production"
+
+// fakeAPI stands in for the DigitalOcean API. Every request is recorded as
+// "METHOD path body" and answered from the handler registered for
+// "METHOD path", or with a 404.
+type fakeAPI struct {
+	requests []string
+	handlers map[string]func(w http.ResponseWriter, r *http.Request)
+}
+
+func newFakeAPI(t *testing.T) (*fakeAPI, *Client) {
+	f := &fakeAPI{handlers: map[string]func(w http.ResponseWriter, r *http.Request){}}
+	s := httptest.NewServer(f)
+	t.Cleanup(s.Close)
+
+	c, err := NewClient("secret", s.URL+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.PollInterval = time.Millisecond
+	return f, c
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	b, _ := ioutil.ReadAll(r.Body)
+	req := strings.TrimSpace(fmt.Sprintf("%s %s %s", r.Method, r.URL.RequestURI(), b))
+	f.requests = append(f.requests, req)
+
+	h, ok := f.handlers[r.Method+" "+r.URL.Path]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"id":"not_found","message":"The resource you requested could not be found."}`)
+		return
+	}
+	h(w, r)
+}
+
+// reply answers with status and the JSON encoding of each body in turn,
+// repeating the last one.
+func reply(status int, bodies ...string) func(w http.ResponseWriter, r *http.Request) {
+	i := 0
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, bodies[i])
+		if i < len(bodies)-1 {
+			i++
+		}
+	}
+}
+
+func droplet(status string, ip string) string {
+	v4 := "[]"
+	if ip != "" {
+		v4 = fmt.Sprintf(`[{"ip_address":%q,"type":"public"}]`, ip)
+	}
+	return fmt.Sprintf(`{"droplet":{"id":42,"name":"demo-production","status":%q,"networks":{"v4":%s},"tags":["buffalo-ocean"]}}`, status, v4)
+}
+
+func TestUploadSSHKey(t *testing.T) {
+	f, c := newFakeAPI(t)
+	f.handlers["POST /v2/account/keys"] = reply(http.StatusCreated, `{"ssh_key":{"id":1,"fingerprint":"from-the-api"}}`)
+
+	fp, err := c.UploadSSHKey(context.Background(), "demo-production", testKey+"\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp != "from-the-api" {
+		t.Errorf("got fingerprint %q", fp)
+	}
+
+	local, _ := Fingerprint(testKey)
+	if got := f.requests[0]; got != "GET /v2/account/keys/"+local {
+		t.Errorf("looked the key up with %q", got)
+	}
+	var body map[string]string
+	if err := json.Unmarshal([]byte(strings.SplitN(f.requests[1], " ", 3)[2]), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["name"] != "demo-production" || body["public_key"] != testKey {
+		t.Errorf("uploaded %v", body)
+	}
+}
+
+func TestUploadSSHKeyThatExists(t *testing.T) {
+	f, c := newFakeAPI(t)
+	fp, _ := Fingerprint(testKey)
+	f.handlers["GET /v2/account/keys/"+fp] = reply(http.StatusOK, `{"ssh_key":{"id":1}}`)
+
+	got, err := c.UploadSSHKey(context.Background(), "demo-production", testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != fp {
+		t.Errorf("got fingerprint %q, want %q", got, fp)
+	}
+	if len(f.requests) != 1 {
+		t.Errorf("made %q", f.requests)
+	}
+}
+
+func TestCreateDroplet(t *testing.T) {
+	f, c := newFakeAPI(t)
+	f.handlers["POST /v2/droplets"] = reply(http.StatusAccepted, droplet("new", ""))
+
+	d, err := c.CreateDroplet(context.Background(), DropletRequest{
+		Name:              "demo-production",
+		Region:            "nyc3",
+		Size:              "s-1vcpu-1gb",
+		Image:             "ubuntu-18-04-x64",
+		Monitoring:        true,
+		SSHKeyFingerprint: "aa:bb",
+		Tags:              []string{"demo-production"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.ID != 42 || d.Status != "new" {
+		t.Errorf("got %+v", d)
+	}
+
+	var body struct {
+		Name       string
+		Region     string
+		Size       string
+		Image      string
+		Monitoring bool
+		SSHKeys    []string `json:"ssh_keys"`
+		Tags       []string
+	}
+	if err := json.Unmarshal([]byte(strings.SplitN(f.requests[0], " ", 3)[2]), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Name != "demo-production" || body.Region != "nyc3" || body.Size != "s-1vcpu-1gb" || body.Image != "ubuntu-18-04-x64" || !body.Monitoring {
+		t.Errorf("created %+v", body)
+	}
+	if !reflect.DeepEqual(body.SSHKeys, []string{"aa:bb"}) {
+		t.Errorf("got ssh keys %q", body.SSHKeys)
+	}
+	if !reflect.DeepEqual(body.Tags, []string{Tag, "demo-production"}) {
+		t.Errorf("got tags %q", body.Tags)
+	}
+}
+
+func TestWaitForDroplet(t *testing.T) {
+	f, c := newFakeAPI(t)
+	f.handlers["GET /v2/droplets/42"] = reply(http.StatusOK, droplet("new", ""), droplet("active", ""), droplet("active", "203.0.113.10"))
+
+	d, err := c.WaitForDroplet(context.Background(), 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.IPv4 != "203.0.113.10" {
+		t.Errorf("got %+v", d)
+	}
+	if len(f.requests) != 3 {
+		t.Errorf("polled %d times, want 3", len(f.requests))
+	}
+}
+
+func TestWaitForDropletTimesOut(t *testing.T) {
+	f, c := newFakeAPI(t)
+	f.handlers["GET /v2/droplets/42"] = reply(http.StatusOK, droplet("new", ""))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.WaitForDroplet(ctx, 42); err == nil {
+		t.Fatal("a droplet that never became active was returned")
+	}
+}
+
+func TestFindDroplet(t *testing.T) {
+	f, c := newFakeAPI(t)
+	f.handlers["GET /v2/droplets"] = reply(http.StatusOK, `{"droplets":[{"id":41,"name":"other"},{"id":42,"name":"demo-production","status":"active"}]}`)
+
+	d, ok, err := c.FindDroplet(context.Background(), "demo-production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || d.ID != 42 {
+		t.Errorf("got %+v, %v", d, ok)
+	}
+	if got := f.requests[0]; !strings.Contains(got, "tag_name=demo-production") {
+		t.Errorf("listed the droplets with %q", got)
+	}
+}
+
+func TestFindDropletThatDoesNotExist(t *testing.T) {
+	_, c := newFakeAPI(t)
+
+	if _, ok, err := c.FindDroplet(context.Background(), "demo-production"); err != nil || ok {
+		t.Errorf("got %v, %v", ok, err)
+	}
+}
+
+func TestDeleteDroplet(t *testing.T) {
+	f, c := newFakeAPI(t)
+	f.handlers["DELETE /v2/droplets/42"] = reply(http.StatusNoContent, ``)
+
+	if err := c.DeleteDroplet(context.Background(), 42); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeleteDroplet(context.Background(), 43); err == nil {
+		t.Error("deleting a droplet that does not exist succeeded")
+	}
+}
+
+func TestDeleteSSHKey(t *testing.T) {
+	f, c := newFakeAPI(t)
+	f.handlers["DELETE /v2/account/keys/aa:bb"] = reply(http.StatusNoContent, ``)
+
+	if err := c.DeleteSSHKey(context.Background(), "aa:bb"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeleteSSHKey(context.Background(), "cc:dd"); err != nil {
+		t.Errorf("deleting a key that is gone failed: %s", err)
+	}
+}
+
+func TestPowerActions(t *testing.T) {
+	f, c := newFakeAPI(t)
+	f.handlers["POST /v2/droplets/42/actions"] = reply(http.StatusCreated, `{"action":{"id":7,"status":"in-progress"}}`)
+	f.handlers["GET /v2/droplets/42/actions/7"] = reply(http.StatusOK, `{"action":{"id":7,"status":"in-progress"}}`, `{"action":{"id":7,"status":"completed"}}`)
+
+	if err := c.Shutdown(context.Background(), 42); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.requests[0]; got != `POST /v2/droplets/42/actions {"type":"shutdown"}` {
+		t.Errorf("got %q", got)
+	}
+	if len(f.requests) != 3 {
+		t.Errorf("made %q", f.requests)
+	}
+
+	f.handlers["GET /v2/droplets/42/actions/7"] = reply(http.StatusOK, `{"action":{"id":7,"status":"errored"}}`)
+	if err := c.PowerOn(context.Background(), 42); err == nil {
+		t.Error("an errored action succeeded")
+	}
+}